@@ -2,14 +2,23 @@ package main
 
 import (
 	"bufio"
+	"crud/pkg/inflect"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+
+	"gopkg.in/yaml.v3"
 )
 
 func getModuleName() string {
@@ -30,23 +39,76 @@ func getModuleName() string {
 }
 
 const extendStructTpl = `// Code generated by generate. DO NOT EDIT.
-package sqlc
-
+package {{.Package}}
+{{if .Imports}}
+import (
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+{{end}}
 {{range .Structs}}
 var (
 	{{.Name}}ColumnsMap = map[string]struct{}{ {{range $i, $e := .ColumnList}}{{if $i}}, {{end}}{{$e}}: {} {{end}} }
 	{{.Name}}Columns = []string{ {{range $i, $e := .ColumnList}}{{if $i}}, {{end}}{{$e}}{{end}} }
+	{{.Name}}ValidationRules = map[string]string{ {{range $i, $e := .Rules}}{{if $i}}, {{end}}{{printf "%q" $e.Column}}: {{printf "%q" $e.Rules}}{{end}} }
+	{{- if .Relations}}
+	{{.Name}}Relations = map[string]string{ {{range $i, $e := .Relations}}{{if $i}}, {{end}}{{printf "%q" $e.Name}}: {{printf "%q" $e.Rule}}{{end}} }
+	{{- end}}
 )
 
 func (m {{.Name}}) TableName() string { return "{{.TableName}}" }
 func (m {{.Name}}) Columns() []string { return {{.Name}}Columns }
 func (m {{.Name}}) ColumnsMap() map[string]struct{} { return {{.Name}}ColumnsMap }
 func (m {{.Name}}) GetId() int64 { return m.ID }
+func (m {{.Name}}) ValidationRules() map[string]string { return {{.Name}}ValidationRules }
+{{- if .SoftDeleteColumn}}
+func (m {{.Name}}) SoftDeleteColumn() string { return "{{.SoftDeleteColumn}}" }
+{{- end}}
+{{- if .Relations}}
+func (m {{.Name}}) Relations() map[string]string { return {{.Name}}Relations }
+{{- end}}
+{{- $model := .Name}}
+{{- range .Relations}}
+{{if .Many}}
+// {{.Method}} 查询关联的{{.Target}},记录不持有数据库句柄,通过q在连接或事务上执行
+func (m {{$model}}) {{.Method}}(ctx context.Context, q *Queries) ([]{{.Target}}, error) {
+	rows, err := q.db.QueryContext(ctx, {{printf "%q" .Query}}, {{.Arg}})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []{{.Target}}
+	for rows.Next() {
+		var i {{.Target}}
+		if err := rows.Scan({{.Scan}}); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+{{else}}
+// {{.Method}} 查询关联的{{.Target}},记录不持有数据库句柄,通过q在连接或事务上执行
+func (m {{$model}}) {{.Method}}(ctx context.Context, q *Queries) ({{.Target}}, error) {
+	row := q.db.QueryRowContext(ctx, {{printf "%q" .Query}}, {{.Arg}})
+	var i {{.Target}}
+	err := row.Scan({{.Scan}})
+	return i, err
+}
+{{end}}
+{{- end}}
 
 type {{.Name}}Update struct {
 	Id int64 ` + "`" + `db:"id" json:"id" param:"id" query:"id" form:"id"` + "`" + `
 	{{- range .Fields -}}
-	{{- if ne .DBName "id"}}
+	{{- if .Updatable}}
 	{{.Name}} *{{.Type}} ` + "`" + `db:"{{.DBName}}" json:"{{.JSONName}},omitempty" param:"{{.DBName}}" query:"{{.DBName}}" form:"{{.DBName}}"` + "`" + `
 	{{- end -}}
 	{{- end}}
@@ -55,28 +117,616 @@ type {{.Name}}Update struct {
 func (m {{.Name}}Update) TableName() string { return "{{.TableName}}" }
 func (m {{.Name}}Update) Columns() []string { return {{.Name}}Columns }
 func (m {{.Name}}Update) GetId() int64 { return m.Id }
+func (m {{.Name}}Update) ValidationRules() map[string]string { return {{.Name}}ValidationRules }
+{{- if .SoftDeleteColumn}}
+func (m {{.Name}}Update) SoftDeleteColumn() string { return "{{.SoftDeleteColumn}}" }
+{{- end}}
 
 {{end}}
 `
 
 type FieldInfo struct {
-	Name     string
-	Type     string
-	DBName   string
-	JSONName string
+	Name      string
+	Type      string
+	DBName    string
+	JSONName  string
+	Updatable bool // 是否允许客户端通过XxxUpdate修改
+}
+
+// 软删除时间列名,表中存在该列时启用软删除
+const softDeleteColumn = "deleted_at"
+
+// 由通用层自动维护、不允许客户端修改的列
+var managedColumns = map[string]struct{}{
+	"id":             {},
+	softDeleteColumn: {},
+	"created_at":     {},
+	"updated_at":     {},
 }
 
 type StructInfo struct {
-	Name       string
-	TableName  string
-	Columns    string
-	ColumnList []string
-	Fields     []FieldInfo
+	Name             string
+	TableName        string
+	Columns          string
+	ColumnList       []string
+	Fields           []FieldInfo
+	SoftDeleteColumn string
+	Rules            []ColumnRules
+	Relations        []RelationInfo
+}
+
+// RelationInfo 根据外键推导出的关联及其加载方法
+type RelationInfo struct {
+	Name   string // 关联名称,include参数中使用
+	Rule   string // 关联规则,格式见sqlx.IRelations
+	Method string // 加载方法名,如LoadAuthor,接收*Queries以便在事务中调用
+	Target string // 关联表的结构体名
+	Many   bool   // 加载方法是否返回列表
+	Query  string // 加载方法执行的SQL
+	Arg    string // 查询参数
+	Scan   string // Scan的参数列表
+}
+
+// ColumnRules 根据建表语句推导出的列校验规则
+type ColumnRules struct {
+	Column string
+	Rules  string
+}
+
+// 命令行参数,与sqlc.yaml中对应条目的out、schema、engine一致
+// 例如: go run cmd/generate.go -out ./db/sqlc/postgres -schema ./db/migrations/postgres -engine postgresql
+var (
+	outDir     = flag.String("out", "./db/sqlc", "sqlc生成代码所在目录,扩展代码写入该目录的models_ex.go")
+	schemaDir  = flag.String("schema", "./db/migrations", "建表语句所在目录")
+	engine     = flag.String("engine", "mysql", "数据库引擎: mysql 或 postgresql")
+	sqlcConfig = flag.String("sqlc", "sqlc.yaml", "sqlc配置文件,读取out对应条目的emit_exact_table_names")
+	genConfig  = flag.String("config", "generate.yaml", "生成器配置文件,不存在时忽略")
+)
+
+// GenerateConfig 生成器配置文件
+//
+//	tables:
+//	  Person: people # 结构体名 -> 表名,覆盖推导出的表名
+type GenerateConfig struct {
+	Tables map[string]string `yaml:"tables"`
+}
+
+// loadConfig 读取生成器配置文件,文件不存在时返回空配置
+func loadConfig(path string) (*GenerateConfig, error) {
+	config := &GenerateConfig{}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// exactTableNames 读取sqlc配置中out与outDir相同的条目的emit_exact_table_names选项
+// 为true时sqlc直接使用表名作为结构体名,否则使用表名的单数形式
+func exactTableNames(path string, out string) (bool, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var config struct {
+		SQL []struct {
+			Gen struct {
+				Go struct {
+					Out                 string `yaml:"out"`
+					EmitExactTableNames bool   `yaml:"emit_exact_table_names"`
+				} `yaml:"go"`
+			} `yaml:"gen"`
+		} `yaml:"sql"`
+	}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, entry := range config.SQL {
+		if filepath.Clean(entry.Gen.Go.Out) == filepath.Clean(out) {
+			return entry.Gen.Go.EmitExactTableNames, nil
+		}
+	}
+	return false, nil
+}
+
+// 结构体注释中指定表名的指令,sqlc会将表注释(COMMENT)写入结构体注释
+var tableDirectiveRe = regexp.MustCompile(`generate:table=(\w+)`)
+
+// resolveTableName 推导结构体对应的表名,优先级依次为:
+// 结构体注释中的generate:table=指令、配置文件中的tables、建表语句中sqlc会生成该结构体的表、按命名规则推导
+func resolveTableName(name string, doc *ast.CommentGroup, config *GenerateConfig, schema map[string]*TableSchema, exact bool) string {
+	if doc != nil {
+		if match := tableDirectiveRe.FindStringSubmatch(doc.Text()); match != nil {
+			return match[1]
+		}
+	}
+	if table, ok := config.Tables[name]; ok {
+		return table
+	}
+	tableNames := make([]string, 0, len(schema))
+	for table := range schema {
+		tableNames = append(tableNames, table)
+	}
+	sort.Strings(tableNames)
+	for _, table := range tableNames {
+		if structNameOf(table, exact) == name {
+			return table
+		}
+	}
+	if exact {
+		return inflect.Snake(name)
+	}
+	return inflect.Plural(inflect.Snake(name))
+}
+
+// structNameOf sqlc为表生成的结构体名
+func structNameOf(table string, exact bool) string {
+	if !exact {
+		table = inflect.Singular(table)
+	}
+	return inflect.Camel(table)
+}
+
+var (
+	createTableRe = regexp.MustCompile("(?is)CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?[`\"]?(\\w+)[`\"]?\\s*\\((.*?)\\)\\s*[^)]*?(?:;|\\z)")
+	foreignKeyRe  = regexp.MustCompile("(?i)FOREIGN\\s+KEY\\s*\\(\\s*[`\"]?(\\w+)[`\"]?\\s*\\)\\s*REFERENCES\\s+[`\"]?(\\w+)[`\"]?\\s*\\(\\s*[`\"]?(\\w+)[`\"]?\\s*\\)")
+	referencesRe  = regexp.MustCompile("(?i)REFERENCES\\s+[`\"]?(\\w+)[`\"]?\\s*\\(\\s*[`\"]?(\\w+)[`\"]?\\s*\\)")
+	columnTypeRe  = regexp.MustCompile("(?i)^(\\w+)(?:\\s*\\(([^)]*)\\))?(\\s+UNSIGNED)?")
+)
+
+// 整数类型的取值范围,依次为有符号最小值、有符号最大值、无符号最大值
+var intRanges = map[string][3]string{
+	"tinyint":   {"-128", "127", "255"},
+	"smallint":  {"-32768", "32767", "65535"},
+	"mediumint": {"-8388608", "8388607", "16777215"},
+	"int":       {"-2147483648", "2147483647", "4294967295"},
+	"integer":   {"-2147483648", "2147483647", "4294967295"},
+	"int2":      {"-32768", "32767", "65535"},
+	"int4":      {"-2147483648", "2147483647", "4294967295"},
+}
+
+// MySQL文本类型的最大字节数,PostgreSQL的text没有长度限制
+var textLengths = map[string]string{
+	"tinytext":   "255",
+	"text":       "65535",
+	"mediumtext": "16777215",
+}
+
+// TableSchema 从建表语句解析出的表结构
+type TableSchema struct {
+	Columns     []string          // 列名,按定义顺序
+	Rules       map[string]string // 列名 -> 校验规则
+	ForeignKeys []ForeignKey      // 外键约束
+}
+
+// ForeignKey 外键约束,Column引用RefTable.RefColumn
+type ForeignKey struct {
+	Column    string
+	RefTable  string
+	RefColumn string
+}
+
+// parseSchema 解析建表语句,返回 表名 -> 表结构
+// NOT NULL且没有默认值的列为required,字符串类型限制最大长度,整数类型限制取值范围,
+// ENUM限制可选值,外键检查引用的记录是否存在
+func parseSchema(dir string) (map[string]*TableSchema, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string]*TableSchema)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range createTableRe.FindAllStringSubmatch(string(content), -1) {
+			columns, foreignKeys := parseColumnsAndForeignKeys(match[2])
+			tables[strings.ToLower(match[1])] = &TableSchema{
+				Columns:     columns,
+				Rules:       parseColumnRules(match[2]),
+				ForeignKeys: foreignKeys,
+			}
+		}
+	}
+	return tables, nil
+}
+
+// parseColumnsAndForeignKeys 解析单个表的列名和外键,包括表级FOREIGN KEY和列级REFERENCES
+func parseColumnsAndForeignKeys(body string) ([]string, []ForeignKey) {
+	var columns []string
+	var foreignKeys []ForeignKey
+	for _, def := range splitDefinitions(body) {
+		if fk := foreignKeyRe.FindStringSubmatch(def); fk != nil {
+			foreignKeys = append(foreignKeys, ForeignKey{Column: fk[1], RefTable: strings.ToLower(fk[2]), RefColumn: fk[3]})
+			continue
+		}
+		fields := strings.Fields(def)
+		if len(fields) < 2 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PRIMARY", "KEY", "INDEX", "UNIQUE", "CONSTRAINT", "CHECK", "FULLTEXT":
+			continue
+		}
+		column := strings.Trim(fields[0], "`\"")
+		columns = append(columns, column)
+		if ref := referencesRe.FindStringSubmatch(def); ref != nil {
+			foreignKeys = append(foreignKeys, ForeignKey{Column: column, RefTable: strings.ToLower(ref[1]), RefColumn: ref[2]})
+		}
+	}
+	return columns, foreignKeys
+}
+
+// isJoinTable 判断表是否为多对多的中间表: 恰好两个外键,其余列只有自动维护的列
+func isJoinTable(table *TableSchema) bool {
+	if len(table.ForeignKeys) != 2 {
+		return false
+	}
+	for _, column := range table.Columns {
+		if column == table.ForeignKeys[0].Column || column == table.ForeignKeys[1].Column {
+			continue
+		}
+		if _, managed := managedColumns[column]; !managed {
+			return false
+		}
+	}
+	return true
+}
+
+// buildRelations 根据外键为结构体推导关联:
+// 外键所在的表belongs_to被引用的表,被引用的表has_many外键所在的表,
+// 中间表两端的表互为many_to_many。只处理引用id列且两端都有结构体的外键
+func buildRelations(schema map[string]*TableSchema, structs []StructInfo) {
+	byTable := make(map[string]*StructInfo, len(structs))
+	for i := range structs {
+		byTable[structs[i].TableName] = &structs[i]
+	}
+	// 按表名顺序处理,保证生成结果稳定
+	tableNames := make([]string, 0, len(schema))
+	for name := range schema {
+		tableNames = append(tableNames, name)
+	}
+	sort.Strings(tableNames)
+
+	for _, tableName := range tableNames {
+		table := schema[tableName]
+		if isJoinTable(table) {
+			left, right := table.ForeignKeys[0], table.ForeignKeys[1]
+			leftInfo, rightInfo := byTable[left.RefTable], byTable[right.RefTable]
+			if leftInfo == nil || rightInfo == nil || left.RefColumn != "id" || right.RefColumn != "id" {
+				continue
+			}
+			addManyToMany(leftInfo, rightInfo, tableName, left.Column, right.Column)
+			addManyToMany(rightInfo, leftInfo, tableName, right.Column, left.Column)
+			continue
+		}
+		owner := byTable[tableName]
+		for _, fk := range table.ForeignKeys {
+			target := byTable[fk.RefTable]
+			if owner == nil || target == nil || fk.RefColumn != "id" {
+				continue
+			}
+			name := strings.TrimSuffix(fk.Column, "_id")
+			if name == fk.Column || !relationNameFree(owner, name) {
+				fmt.Fprintf(os.Stderr, "skip belongs_to relation for %s.%s: no usable relation name\n", tableName, fk.Column)
+			} else {
+				owner.Relations = append(owner.Relations, RelationInfo{
+					Name:   name,
+					Rule:   fmt.Sprintf("belongs_to=%s,foreign_key=%s", target.TableName, fk.Column),
+					Target: target.Name,
+					Query:  fmt.Sprintf("SELECT %s FROM %s WHERE id = %s%s LIMIT 1", selectList(target, ""), target.TableName, placeholder(), notDeleted(target, "")),
+					Arg:    "m." + fieldName(owner, fk.Column),
+					Scan:   scanList(target),
+				})
+			}
+
+			name = owner.TableName
+			if !relationNameFree(target, name) {
+				name = owner.TableName + "_by_" + strings.TrimSuffix(fk.Column, "_id")
+			}
+			target.Relations = append(target.Relations, RelationInfo{
+				Name:   name,
+				Rule:   fmt.Sprintf("has_many=%s,foreign_key=%s", owner.TableName, fk.Column),
+				Target: owner.Name,
+				Many:   true,
+				Query:  fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s%s ORDER BY id", selectList(owner, ""), owner.TableName, fk.Column, placeholder(), notDeleted(owner, "")),
+				Arg:    "m.ID",
+				Scan:   scanList(owner),
+			})
+		}
+	}
+	for i := range structs {
+		for j := range structs[i].Relations {
+			structs[i].Relations[j].Method = "Load" + inflect.Camel(structs[i].Relations[j].Name)
+		}
+	}
+}
+
+// addManyToMany 为owner添加通过中间表through关联target的关联
+func addManyToMany(owner, target *StructInfo, through, foreignKey, references string) {
+	name := target.TableName
+	if !relationNameFree(owner, name) {
+		fmt.Fprintf(os.Stderr, "skip many_to_many relation %s.%s: name already used\n", owner.TableName, name)
+		return
+	}
+	owner.Relations = append(owner.Relations, RelationInfo{
+		Name:   name,
+		Rule:   fmt.Sprintf("many_to_many=%s,through=%s,foreign_key=%s,references=%s", target.TableName, through, foreignKey, references),
+		Target: target.Name,
+		Many:   true,
+		Query: fmt.Sprintf("SELECT %s FROM %s JOIN %s ON %s.%s = %s.id WHERE %s.%s = %s%s ORDER BY %s.id",
+			selectList(target, target.TableName), target.TableName, through, through, references, target.TableName,
+			through, foreignKey, placeholder(), notDeleted(target, target.TableName), target.TableName),
+		Arg:  "m.ID",
+		Scan: scanList(target),
+	})
+}
+
+// relationNameFree 关联名称与结构体的列名和已有关联都不冲突,返回结果中关联与列共用同一层键
+func relationNameFree(info *StructInfo, name string) bool {
+	for _, field := range info.Fields {
+		if field.DBName == name || field.JSONName == name {
+			return false
+		}
+	}
+	for _, relation := range info.Relations {
+		if relation.Name == name {
+			return false
+		}
+	}
+	return true
+}
+
+// selectList 结构体全部列的查询列表,qualifier不为空时以表名限定列名
+func selectList(info *StructInfo, qualifier string) string {
+	columns := make([]string, len(info.Fields))
+	for i, field := range info.Fields {
+		columns[i] = field.DBName
+		if qualifier != "" {
+			columns[i] = qualifier + "." + field.DBName
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// scanList 按列顺序扫描到变量i的参数列表
+func scanList(info *StructInfo) string {
+	args := make([]string, len(info.Fields))
+	for i, field := range info.Fields {
+		args[i] = "&i." + field.Name
+	}
+	return strings.Join(args, ", ")
+}
+
+// fieldName 列对应的结构体字段名
+func fieldName(info *StructInfo, column string) string {
+	for _, field := range info.Fields {
+		if field.DBName == column {
+			return field.Name
+		}
+	}
+	return inflect.Camel(column)
+}
+
+// notDeleted 关联表声明了软删除列时排除已删除的记录
+func notDeleted(info *StructInfo, qualifier string) string {
+	if info.SoftDeleteColumn == "" {
+		return ""
+	}
+	if qualifier != "" {
+		return fmt.Sprintf(" AND %s.%s IS NULL", qualifier, info.SoftDeleteColumn)
+	}
+	return fmt.Sprintf(" AND %s IS NULL", info.SoftDeleteColumn)
+}
+
+// placeholder 加载方法中唯一参数的占位符
+func placeholder() string {
+	if *engine == "postgresql" {
+		return "$1"
+	}
+	return "?"
+}
+
+// parseColumnRules 解析单个表的列定义
+func parseColumnRules(body string) map[string]string {
+	rules := make(map[string][]string)
+	var order []string
+	add := func(column string, rule string) {
+		if _, ok := rules[column]; !ok {
+			order = append(order, column)
+		}
+		rules[column] = append(rules[column], rule)
+	}
+	for _, def := range splitDefinitions(body) {
+		if fk := foreignKeyRe.FindStringSubmatch(def); fk != nil {
+			add(fk[1], fmt.Sprintf("fk=%s.%s", fk[2], fk[3]))
+			continue
+		}
+		fields := strings.Fields(def)
+		if len(fields) < 2 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PRIMARY", "KEY", "INDEX", "UNIQUE", "CONSTRAINT", "CHECK", "FULLTEXT":
+			continue
+		}
+		column := strings.Trim(fields[0], "`\"")
+		if _, managed := managedColumns[column]; managed || column == "version" {
+			continue
+		}
+		upper := strings.ToUpper(def)
+		// 自增列(MySQL的AUTO_INCREMENT,PostgreSQL的SERIAL和IDENTITY)由数据库生成
+		definition := strings.ToUpper(strings.TrimPrefix(def, fields[0]))
+		generated := strings.Contains(definition, "AUTO_INCREMENT") || strings.HasSuffix(strings.ToUpper(fields[1]), "SERIAL") || strings.Contains(definition, " GENERATED ")
+		if strings.Contains(upper, "NOT NULL") && !strings.Contains(upper, "DEFAULT") && !generated {
+			add(column, "required")
+		}
+		for _, rule := range typeRules(strings.TrimSpace(strings.TrimPrefix(def, fields[0]))) {
+			add(column, rule)
+		}
+		if ref := referencesRe.FindStringSubmatch(def); ref != nil {
+			add(column, fmt.Sprintf("fk=%s.%s", ref[1], ref[2]))
+		}
+	}
+	result := make(map[string]string, len(order))
+	for _, column := range order {
+		result[column] = strings.Join(rules[column], ",")
+	}
+	return result
+}
+
+// typeRules 根据列类型推导长度、取值范围和可选值规则
+func typeRules(columnType string) []string {
+	// PostgreSQL的character varying(n)、character(n)等同于varchar(n)、char(n)
+	lower := strings.ToLower(columnType)
+	if strings.HasPrefix(lower, "character varying") {
+		columnType = "varchar" + columnType[len("character varying"):]
+	} else if strings.HasPrefix(lower, "character") {
+		columnType = "char" + columnType[len("character"):]
+	}
+	match := columnTypeRe.FindStringSubmatch(columnType)
+	if match == nil {
+		return nil
+	}
+	name := strings.ToLower(match[1])
+	switch name {
+	case "varchar", "char":
+		if match[2] != "" {
+			return []string{"max=" + strings.TrimSpace(match[2])}
+		}
+	case "enum":
+		var options []string
+		for _, option := range strings.Split(match[2], ",") {
+			option = strings.Trim(strings.TrimSpace(option), "'\"")
+			// 含空格的取值无法用oneof表示
+			if option == "" || strings.ContainsAny(option, " ,") {
+				return nil
+			}
+			options = append(options, option)
+		}
+		return []string{"oneof=" + strings.Join(options, " ")}
+	}
+	if length, ok := textLengths[name]; ok && *engine == "mysql" {
+		return []string{"maxbytes=" + length}
+	}
+	if r, ok := intRanges[name]; ok {
+		if match[3] != "" {
+			return []string{"min=0", "max=" + r[2]}
+		}
+		return []string{"min=" + r[0], "max=" + r[1]}
+	}
+	if name == "bigint" && match[3] != "" {
+		return []string{"min=0"}
+	}
+	return nil
+}
+
+// splitDefinitions 按顶层逗号拆分列定义,忽略括号内的逗号
+func splitDefinitions(body string) []string {
+	var defs []string
+	depth, start := 0, 0
+	for i, r := range body {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				defs = append(defs, strings.TrimSpace(body[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(body[start:]); rest != "" {
+		defs = append(defs, rest)
+	}
+	return defs
+}
+
+// structRules 按结构体列顺序整理表的校验规则
+func structRules(tableRules map[string]string, columns []string) []ColumnRules {
+	var result []ColumnRules
+	for _, column := range columns {
+		if rules, ok := tableRules[column]; ok && rules != "" {
+			result = append(result, ColumnRules{Column: column, Rules: rules})
+		}
+	}
+	return result
 }
 
 type TemplateData struct {
 	ModuleName string
+	Package    string
 	Structs    []StructInfo
+	Imports    []string // 生成文件导入的包,见fileImports
+}
+
+// 类型表达式中的包名限定符,例如time.Time中的time
+var packageQualifierRe = regexp.MustCompile(`\b([A-Za-z_]\w*)\.`)
+
+// fileImports 返回生成文件需要导入的包,按路径排序
+// 关联加载方法使用context,XxxUpdate字段类型引用的包按models.go的导入解析,
+// sql.NullXxx转换后的time.Time和保留的sql.Xxx不依赖models.go是否导入了对应的包
+func fileImports(structs []StructInfo, imports []*ast.ImportSpec) ([]string, error) {
+	known := map[string]string{"time": `"time"`, "sql": `"database/sql"`}
+	for _, spec := range imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		name := path.Base(importPath)
+		if spec.Name != nil && spec.Name.Name != name {
+			name = spec.Name.Name
+			known[name] = name + " " + spec.Path.Value
+			continue
+		}
+		known[name] = spec.Path.Value
+	}
+
+	used := make(map[string]string)
+	for _, info := range structs {
+		if len(info.Relations) > 0 {
+			used["context"] = `"context"`
+		}
+		for _, field := range info.Fields {
+			if !field.Updatable {
+				continue
+			}
+			for _, match := range packageQualifierRe.FindAllStringSubmatch(field.Type, -1) {
+				spec, ok := known[match[1]]
+				if !ok {
+					return nil, fmt.Errorf("package %s used by %s.%s is not imported in models.go", match[1], info.Name, field.Name)
+				}
+				used[match[1]] = spec
+			}
+		}
+	}
+
+	result := make([]string, 0, len(used))
+	for _, spec := range used {
+		result = append(result, spec)
+	}
+	// 按导入路径排序,与gofmt一致
+	sort.Slice(result, func(i, j int) bool {
+		return importPathOf(result[i]) < importPathOf(result[j])
+	})
+	return result, nil
+}
+
+// importPathOf 返回导入声明中的路径
+func importPathOf(spec string) string {
+	return spec[strings.Index(spec, `"`):]
 }
 
 func getFieldType(expr ast.Expr) string {
@@ -104,18 +754,43 @@ func getFieldType(expr ast.Expr) string {
 	case *ast.Ident:
 		return t.Name
 	}
-	return fmt.Sprintf("%v", expr)
+	return types.ExprString(expr)
 }
 
 func main() {
+	flag.Parse()
+
 	// 获取模块名
 	moduleName := getModuleName()
 
+	if err := generate(moduleName); err != nil {
+		panic(err)
+	}
+}
+
+// generate 读取命令行参数指定的models.go和建表语句,生成models_ex.go
+func generate(moduleName string) error {
 	// 解析models.go文件
 	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, "./db/sqlc/models.go", nil, parser.ParseComments)
+	f, err := parser.ParseFile(fset, filepath.Join(*outDir, "models.go"), nil, parser.ParseComments)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	// 解析建表语句,推导校验规则
+	schema, err := parseSchema(*schemaDir)
+	if err != nil {
+		return err
+	}
+
+	// 读取表名推导需要的配置
+	config, err := loadConfig(*genConfig)
+	if err != nil {
+		return err
+	}
+	exact, err := exactTableNames(*sqlcConfig, *outDir)
+	if err != nil {
+		return err
 	}
 
 	var structs []StructInfo
@@ -138,10 +813,17 @@ func main() {
 				continue
 			}
 
-			// 获取结构体信息
+			// 获取结构体信息,单独声明的类型注释在GenDecl上
+			doc := typeSpec.Doc
+			if doc == nil {
+				doc = genDecl.Doc
+			}
 			info := StructInfo{
 				Name:      typeSpec.Name.Name,
-				TableName: strings.ToLower(typeSpec.Name.Name) + "s", // 简单处理表名
+				TableName: resolveTableName(typeSpec.Name.Name, doc, config, schema, exact),
+			}
+			if _, ok := schema[info.TableName]; !ok && len(schema) > 0 {
+				fmt.Fprintf(os.Stderr, "table %s for %s not found in schema, add a generate:table directive or an entry in %s\n", info.TableName, info.Name, *genConfig)
 			}
 
 			// 获取字段名
@@ -166,41 +848,63 @@ func main() {
 					if strings.Contains(tag, "json:") {
 						jsonTag = strings.Split(strings.Split(tag, "json:\"")[1], "\"")[0]
 					}
+					_, managed := managedColumns[dbTag]
 					fields = append(fields, FieldInfo{
-						Name:     field.Names[0].Name,
-						Type:     getFieldType(field.Type),
-						DBName:   dbTag,
-						JSONName: jsonTag,
+						Name:      field.Names[0].Name,
+						Type:      getFieldType(field.Type),
+						DBName:    dbTag,
+						JSONName:  jsonTag,
+						Updatable: !managed,
 					})
+					if dbTag == softDeleteColumn {
+						info.SoftDeleteColumn = softDeleteColumn
+					}
 				}
 			}
 			info.Fields = fields
 
+			var dbNames []string
+			for _, field := range fields {
+				dbNames = append(dbNames, field.DBName)
+			}
+			if table, ok := schema[info.TableName]; ok {
+				info.Rules = structRules(table.Rules, dbNames)
+			}
+
 			structs = append(structs, info)
 		}
 	}
 
+	// 根据外键推导关联
+	buildRelations(schema, structs)
+	imports, err := fileImports(structs, f.Imports)
+	if err != nil {
+		return err
+	}
+
 	// 生成扩展结构体文件
 	tpl := template.Must(template.New("extend").Parse(extendStructTpl))
 
-	outDir := "./db/sqlc"
-	if err := os.MkdirAll(outDir, 0755); err != nil {
-		panic(err)
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
 	}
 
-	outFile := filepath.Join(outDir, "models_ex.go")
+	outFile := filepath.Join(*outDir, "models_ex.go")
 	outFileHandle, err := os.Create(outFile)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer outFileHandle.Close()
 
 	data := TemplateData{
 		ModuleName: moduleName,
+		Package:    f.Name.Name,
 		Structs:    structs,
+		Imports:    imports,
 	}
 
 	if err := tpl.Execute(outFileHandle, data); err != nil {
-		panic(err)
+		return err
 	}
+	return outFileHandle.Close()
 }
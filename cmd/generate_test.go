@@ -0,0 +1,401 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// withEngine 在测试期间切换数据库引擎参数
+func withEngine(t *testing.T, name string) {
+	t.Helper()
+	withFlag(t, engine, name)
+}
+
+// withFlag 在测试期间修改命令行参数
+func withFlag(t *testing.T, flag *string, value string) {
+	t.Helper()
+	previous := *flag
+	*flag = value
+	t.Cleanup(func() { *flag = previous })
+}
+
+func TestTypeRules(t *testing.T) {
+	tests := []struct {
+		engine     string
+		columnType string
+		want       []string
+	}{
+		{"mysql", "varchar(64) NOT NULL", []string{"max=64"}},
+		{"mysql", "CHAR(2)", []string{"max=2"}},
+		{"postgresql", "character varying(32)", []string{"max=32"}},
+		{"postgresql", "character(3)", []string{"max=3"}},
+		{"mysql", "text", []string{"maxbytes=65535"}},
+		{"mysql", "TINYTEXT", []string{"maxbytes=255"}},
+		{"mysql", "mediumtext NOT NULL", []string{"maxbytes=16777215"}},
+		{"postgresql", "text", nil},
+		{"mysql", "tinyint", []string{"min=-128", "max=127"}},
+		{"mysql", "tinyint(1) unsigned", []string{"min=0", "max=255"}},
+		{"mysql", "int UNSIGNED NOT NULL", []string{"min=0", "max=4294967295"}},
+		{"postgresql", "int4", []string{"min=-2147483648", "max=2147483647"}},
+		{"mysql", "bigint unsigned", []string{"min=0"}},
+		{"mysql", "bigint", nil},
+		{"mysql", "enum('draft','published') NOT NULL", []string{"oneof=draft published"}},
+		{"mysql", "enum('in stock','sold')", nil},
+		{"mysql", "datetime", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.engine+" "+tt.columnType, func(t *testing.T) {
+			withEngine(t, tt.engine)
+			if got := typeRules(tt.columnType); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("typeRules(%q) = %v, want %v", tt.columnType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitDefinitions(t *testing.T) {
+	body := "id BIGINT, price DECIMAL(10, 2) NOT NULL,\n status ENUM('a','b'), PRIMARY KEY (id, status) "
+	want := []string{"id BIGINT", "price DECIMAL(10, 2) NOT NULL", "status ENUM('a','b')", "PRIMARY KEY (id, status)"}
+	if got := splitDefinitions(body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("splitDefinitions = %q, want %q", got, want)
+	}
+}
+
+func TestParseColumnRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine string
+		body   string
+		want   map[string]string
+	}{
+		{
+			name:   "mysql",
+			engine: "mysql",
+			body: "`id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+				"`title` varchar(128) NOT NULL,\n" +
+				"`status` enum('draft','published') NOT NULL DEFAULT 'draft',\n" +
+				"`stock` int unsigned NOT NULL,\n" +
+				"`author_id` BIGINT NOT NULL,\n" +
+				"`version` int NOT NULL,\n" +
+				"`created_at` datetime NOT NULL,\n" +
+				"`deleted_at` datetime,\n" +
+				"KEY `idx_title` (`title`),\n" +
+				"FOREIGN KEY (`author_id`) REFERENCES `authors`(`id`)",
+			want: map[string]string{
+				"title":     "required,max=128",
+				"status":    "oneof=draft published",
+				"stock":     "required,min=0,max=4294967295",
+				"author_id": "required,fk=authors.id",
+			},
+		},
+		{
+			name:   "postgresql",
+			engine: "postgresql",
+			body: "id BIGSERIAL PRIMARY KEY,\n" +
+				"name text NOT NULL,\n" +
+				"code character varying(8),\n" +
+				"seq integer GENERATED ALWAYS AS IDENTITY,\n" +
+				"owner_id BIGINT NOT NULL REFERENCES users(id)",
+			want: map[string]string{
+				"name":     "required",
+				"code":     "max=8",
+				"seq":      "min=-2147483648,max=2147483647",
+				"owner_id": "required,fk=users.id",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEngine(t, tt.engine)
+			if got := parseColumnRules(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseColumnRules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchema(t *testing.T) {
+	withEngine(t, "mysql")
+	dir := t.TempDir()
+	schema := "CREATE TABLE IF NOT EXISTS `Authors` (\n" +
+		"  id   BIGINT  NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+		"  name varchar(64) NOT NULL\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n\n" +
+		"create table books (\n" +
+		"  id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+		"  title text NOT NULL\n" +
+		");\n"
+	if err := os.WriteFile(filepath.Join(dir, "scheme.sql"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("CREATE TABLE ignored (id int)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := parseSchema(dir)
+	if err != nil {
+		t.Fatalf("parseSchema: %v", err)
+	}
+	want := map[string]*TableSchema{
+		"authors": {Columns: []string{"id", "name"}, Rules: map[string]string{"name": "required,max=64"}},
+		"books":   {Columns: []string{"id", "title"}, Rules: map[string]string{"title": "required,maxbytes=65535"}},
+	}
+	if !reflect.DeepEqual(tables, want) {
+		for name, table := range tables {
+			t.Logf("%s: %+v", name, *table)
+		}
+		t.Fatalf("parseSchema returned unexpected tables")
+	}
+}
+
+func TestParseColumnsAndForeignKeys(t *testing.T) {
+	body := "id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+		"`book_id` BIGINT NOT NULL,\n" +
+		"\"tag_id\" BIGINT NOT NULL REFERENCES \"Tags\" (\"id\"),\n" +
+		"created_at datetime,\n" +
+		"PRIMARY KEY (book_id, tag_id),\n" +
+		"CONSTRAINT fk_book FOREIGN KEY (`book_id`) REFERENCES `books` (`id`) ON DELETE CASCADE"
+	columns, foreignKeys := parseColumnsAndForeignKeys(body)
+	if want := []string{"id", "book_id", "tag_id", "created_at"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("columns = %v, want %v", columns, want)
+	}
+	want := []ForeignKey{
+		{Column: "tag_id", RefTable: "tags", RefColumn: "id"},
+		{Column: "book_id", RefTable: "books", RefColumn: "id"},
+	}
+	if !reflect.DeepEqual(foreignKeys, want) {
+		t.Errorf("foreign keys = %+v, want %+v", foreignKeys, want)
+	}
+}
+
+func TestIsJoinTable(t *testing.T) {
+	bookTags := []ForeignKey{{Column: "book_id", RefTable: "books", RefColumn: "id"}, {Column: "tag_id", RefTable: "tags", RefColumn: "id"}}
+	tests := []struct {
+		name  string
+		table *TableSchema
+		want  bool
+	}{
+		{"two foreign keys", &TableSchema{Columns: []string{"book_id", "tag_id"}, ForeignKeys: bookTags}, true},
+		{"managed columns are allowed", &TableSchema{Columns: []string{"id", "book_id", "tag_id", "created_at"}, ForeignKeys: bookTags}, true},
+		{"extra data column", &TableSchema{Columns: []string{"book_id", "tag_id", "position"}, ForeignKeys: bookTags}, false},
+		{"one foreign key", &TableSchema{Columns: []string{"id", "title", "author_id"}, ForeignKeys: bookTags[:1]}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isJoinTable(tt.table); got != tt.want {
+				t.Fatalf("isJoinTable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRelations(t *testing.T) {
+	withEngine(t, "mysql")
+	field := func(name, column string) FieldInfo { return FieldInfo{Name: name, DBName: column, JSONName: column} }
+	structs := []StructInfo{
+		{Name: "Author", TableName: "authors", Fields: []FieldInfo{field("ID", "id"), field("Name", "name")}},
+		{Name: "Book", TableName: "books", Fields: []FieldInfo{field("ID", "id"), field("AuthorID", "author_id")}},
+		{Name: "Tag", TableName: "tags", Fields: []FieldInfo{field("ID", "id")}},
+	}
+	schema := map[string]*TableSchema{
+		"authors": {Columns: []string{"id", "name"}},
+		"books": {Columns: []string{"id", "author_id"}, ForeignKeys: []ForeignKey{
+			{Column: "author_id", RefTable: "authors", RefColumn: "id"},
+		}},
+		"tags": {Columns: []string{"id"}},
+		"book_tags": {Columns: []string{"book_id", "tag_id"}, ForeignKeys: []ForeignKey{
+			{Column: "book_id", RefTable: "books", RefColumn: "id"},
+			{Column: "tag_id", RefTable: "tags", RefColumn: "id"},
+		}},
+	}
+	buildRelations(schema, structs)
+
+	rules := func(info StructInfo) map[string]string {
+		result := make(map[string]string)
+		for _, relation := range info.Relations {
+			result[relation.Name+" "+relation.Method] = relation.Rule
+		}
+		return result
+	}
+	tests := []struct {
+		info StructInfo
+		want map[string]string
+	}{
+		{structs[0], map[string]string{"books LoadBooks": "has_many=books,foreign_key=author_id"}},
+		{structs[1], map[string]string{
+			"author LoadAuthor": "belongs_to=authors,foreign_key=author_id",
+			"tags LoadTags":     "many_to_many=tags,through=book_tags,foreign_key=book_id,references=tag_id",
+		}},
+		{structs[2], map[string]string{"books LoadBooks": "many_to_many=books,through=book_tags,foreign_key=tag_id,references=book_id"}},
+	}
+	for _, tt := range tests {
+		if got := rules(tt.info); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s relations = %v, want %v", tt.info.Name, got, tt.want)
+		}
+	}
+}
+
+// 生成测试使用的sqlc代码,只保留生成文件引用的部分
+const (
+	testQueries = `package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+type DBTX interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+type Queries struct {
+	db DBTX
+}
+`
+	testModels = `package models
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+type Author struct {
+	ID       int64           ` + "`db:\"id\" json:\"id\"`" + `
+	Name     string          ` + "`db:\"name\" json:\"name\"`" + `
+	BornAt   sql.NullTime    ` + "`db:\"born_at\" json:\"born_at\"`" + `
+	Meta     json.RawMessage ` + "`db:\"meta\" json:\"meta\"`" + `
+	Avatar   []byte          ` + "`db:\"avatar\" json:\"avatar\"`" + `
+}
+
+type Book struct {
+	ID       int64  ` + "`db:\"id\" json:\"id\"`" + `
+	Title    string ` + "`db:\"title\" json:\"title\"`" + `
+	AuthorID int64  ` + "`db:\"author_id\" json:\"author_id\"`" + `
+}
+`
+	testTables = `CREATE TABLE authors (
+  id      BIGINT      NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  name    varchar(64) NOT NULL,
+  born_at datetime,
+  meta    json,
+  avatar  blob
+);
+CREATE TABLE books (
+  id        BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  title     text   NOT NULL,
+  author_id BIGINT NOT NULL REFERENCES authors (id)
+);
+`
+)
+
+// 生成的代码与sqlc生成的代码一起通过类型检查
+func TestGenerateCompiles(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema")
+	files := map[string]string{
+		filepath.Join(dir, "db.go"):         testQueries,
+		filepath.Join(dir, "models.go"):     testModels,
+		filepath.Join(schema, "scheme.sql"): testTables,
+	}
+	if err := os.MkdirAll(schema, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	withEngine(t, "mysql")
+	withFlag(t, outDir, dir)
+	withFlag(t, schemaDir, schema)
+	withFlag(t, sqlcConfig, filepath.Join(dir, "sqlc.yaml"))
+	withFlag(t, genConfig, filepath.Join(dir, "generate.yaml"))
+	if err := generate("example.com/models"); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, name := range []string{"db.go", "models.go", "models_ex.go"} {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", name, err)
+		}
+		parsed = append(parsed, f)
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := config.Check("models", fset, parsed, nil)
+	if err != nil {
+		t.Fatalf("generated code does not compile: %v", err)
+	}
+
+	// NullTime在XxxUpdate中转换为time.Time,其余类型保持不变
+	update := pkg.Scope().Lookup("AuthorUpdate").Type().Underlying().(*types.Struct)
+	got := make(map[string]string)
+	for i := 0; i < update.NumFields(); i++ {
+		got[update.Field(i).Name()] = update.Field(i).Type().String()
+	}
+	want := map[string]string{
+		"Id":     "int64",
+		"Name":   "*string",
+		"BornAt": "*time.Time",
+		"Meta":   "*encoding/json.RawMessage",
+		"Avatar": "*[]byte",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AuthorUpdate fields = %v, want %v", got, want)
+	}
+}
+
+func TestFileImports(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "models.go", `package models
+
+import (
+	"database/sql"
+	pg "github.com/jackc/pgx/v5/pgtype"
+	"github.com/google/uuid"
+)
+`, parser.ImportsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := func(types ...string) []FieldInfo {
+		var result []FieldInfo
+		for _, typ := range types {
+			result = append(result, FieldInfo{Name: "F", Type: typ, Updatable: true})
+		}
+		return result
+	}
+	tests := []struct {
+		name    string
+		structs []StructInfo
+		want    []string
+		wantErr bool
+	}{
+		{"builtin types", []StructInfo{{Fields: fields("int64", "[]byte")}}, []string{}, false},
+		{"relations use context", []StructInfo{{Relations: []RelationInfo{{}}}}, []string{`"context"`}, false},
+		{"null time", []StructInfo{{Fields: fields("time.Time", "sql.NullInt32")}}, []string{`"database/sql"`, `"time"`}, false},
+		{"imported packages", []StructInfo{{Fields: fields("uuid.UUID", "pg.Numeric")}}, []string{`"github.com/google/uuid"`, `pg "github.com/jackc/pgx/v5/pgtype"`}, false},
+		{"managed fields are skipped", []StructInfo{{Fields: []FieldInfo{{Type: "time.Time"}}}}, []string{}, false},
+		{"unknown package", []StructInfo{{Fields: fields("decimal.Decimal")}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fileImports(tt.structs, f.Imports)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("imports = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	sqlx "crud/db/sqlx"
+	"crud/handler"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 导出OpenAPI文档: go run ./cmd/openapi -o openapi.json
+func main() {
+	output := flag.String("o", "", "输出文件,为空时输出到标准输出")
+	flag.Parse()
+
+	// 只注册路由以构建文档,不连接数据库
+	doc := handler.RegisterRoutes(echo.New(), sqlx.NewRepository(nil, sqlx.MySQL))
+
+	data, err := json.MarshalIndent(doc.Document(), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		panic(err)
+	}
+}
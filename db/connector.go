@@ -6,6 +6,8 @@ import (
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
 type DBConfig struct {
@@ -18,7 +20,8 @@ type DBConfig struct {
 
 // NewMySQLConnector 创建一个新的 MySQL 连接器
 func NewMySQLConnector(config DBConfig) (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	// clientFoundRows使UPDATE返回匹配行数,值未变化时不会被误判为记录不存在
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&clientFoundRows=true",
 		config.User,
 		config.Password,
 		config.Host,
@@ -43,3 +46,53 @@ func NewMySQLConnector(config DBConfig) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// NewPostgresConnector 创建一个新的 PostgreSQL 连接器,驱动名为postgres,与sqlx.Postgres方言对应
+func NewPostgresConnector(config DBConfig) (*sql.DB, error) {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		config.Host,
+		config.Port,
+		config.User,
+		config.Password,
+		config.DBName,
+	)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("连接数据库失败: %v", err)
+	}
+
+	// 设置连接池参数
+	db.SetMaxIdleConns(10)           // 最大空闲连接数
+	db.SetMaxOpenConns(100)          // 最大打开连接数
+	db.SetConnMaxLifetime(time.Hour) // 连接最大生命周期
+
+	// 测试连接
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("ping 数据库失败: %v", err)
+	}
+
+	return db, nil
+}
+
+// NewSQLiteConnector 创建一个新的 SQLite 连接器,使用纯Go实现的驱动,无需外部数据库服务
+// path为":memory:"时使用内存数据库,适合在测试中使用
+func NewSQLiteConnector(path string) (*sql.DB, error) {
+	// 开启外键约束,并在数据库被锁定时等待而不是立即失败
+	dsn := path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("连接数据库失败: %v", err)
+	}
+
+	// SQLite同一时间只允许一个写入者,内存数据库的每个连接都是独立的库,因此只使用一个连接
+	db.SetMaxOpenConns(1)
+
+	// 测试连接
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("ping 数据库失败: %v", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,12 @@
+CREATE TABLE authors (
+  id   BIGSERIAL PRIMARY KEY,
+  name text      NOT NULL,
+  bio  text
+);
+
+CREATE TABLE books (
+  id   BIGSERIAL PRIMARY KEY,
+  title text    NOT NULL,
+  author_id BIGINT NOT NULL,
+  FOREIGN KEY (author_id) REFERENCES authors(id)
+);
@@ -0,0 +1,46 @@
+-- name: CreateAuthor :exec
+INSERT INTO authors (
+  name, bio
+) VALUES (
+  $1, $2
+);
+
+-- name: GetAuthor :one
+SELECT * FROM authors
+WHERE id = $1 LIMIT 1;
+
+-- name: ListAuthors :many
+SELECT * FROM authors;
+
+-- name: ListAuthorsByIds :many
+SELECT * FROM authors
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: ListAuthorsPaginated :many
+SELECT * FROM authors
+LIMIT $1
+OFFSET $2;
+
+-- name: DeleteAuthor :exec
+DELETE FROM authors
+WHERE id = $1;
+
+-- name: DeleteAuthors :exec
+DELETE FROM authors
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: UpdateAuthor :exec
+UPDATE authors
+SET name = $1, bio = $2
+WHERE id = $3;
+
+-- name: GetAuthorWithBooks :many
+SELECT 
+    a.id as author_id,
+    a.name as author_name,
+    a.bio as author_bio,
+    b.id as book_id,
+    b.title as book_title
+FROM authors a
+LEFT JOIN books b ON a.id = b.author_id
+WHERE a.id = $1;
@@ -1,16 +1,49 @@
 // Code generated by generate. DO NOT EDIT.
 package sqlc
 
+import (
+	"context"
+)
+
 
 var (
 	AuthorColumnsMap = map[string]struct{}{ "id": {} , "name": {} , "bio": {}  }
 	AuthorColumns = []string{ "id", "name", "bio" }
+	AuthorValidationRules = map[string]string{ "name": "required,maxbytes=65535", "bio": "maxbytes=65535" }
+	AuthorRelations = map[string]string{ "books": "has_many=books,foreign_key=author_id" }
 )
 
 func (m Author) TableName() string { return "authors" }
 func (m Author) Columns() []string { return AuthorColumns }
 func (m Author) ColumnsMap() map[string]struct{} { return AuthorColumnsMap }
 func (m Author) GetId() int64 { return m.ID }
+func (m Author) ValidationRules() map[string]string { return AuthorValidationRules }
+func (m Author) Relations() map[string]string { return AuthorRelations }
+
+// LoadBooks 查询关联的Book,记录不持有数据库句柄,通过q在连接或事务上执行
+func (m Author) LoadBooks(ctx context.Context, q *Queries) ([]Book, error) {
+	rows, err := q.db.QueryContext(ctx, "SELECT id, title, author_id FROM books WHERE author_id = ? ORDER BY id", m.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Book
+	for rows.Next() {
+		var i Book
+		if err := rows.Scan(&i.ID, &i.Title, &i.AuthorID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 
 type AuthorUpdate struct {
 	Id int64 `db:"id" json:"id" param:"id" query:"id" form:"id"`
@@ -21,17 +54,31 @@ type AuthorUpdate struct {
 func (m AuthorUpdate) TableName() string { return "authors" }
 func (m AuthorUpdate) Columns() []string { return AuthorColumns }
 func (m AuthorUpdate) GetId() int64 { return m.Id }
+func (m AuthorUpdate) ValidationRules() map[string]string { return AuthorValidationRules }
 
 
 var (
 	BookColumnsMap = map[string]struct{}{ "id": {} , "title": {} , "author_id": {}  }
 	BookColumns = []string{ "id", "title", "author_id" }
+	BookValidationRules = map[string]string{ "title": "required,maxbytes=65535", "author_id": "required,fk=authors.id" }
+	BookRelations = map[string]string{ "author": "belongs_to=authors,foreign_key=author_id" }
 )
 
 func (m Book) TableName() string { return "books" }
 func (m Book) Columns() []string { return BookColumns }
 func (m Book) ColumnsMap() map[string]struct{} { return BookColumnsMap }
 func (m Book) GetId() int64 { return m.ID }
+func (m Book) ValidationRules() map[string]string { return BookValidationRules }
+func (m Book) Relations() map[string]string { return BookRelations }
+
+// LoadAuthor 查询关联的Author,记录不持有数据库句柄,通过q在连接或事务上执行
+func (m Book) LoadAuthor(ctx context.Context, q *Queries) (Author, error) {
+	row := q.db.QueryRowContext(ctx, "SELECT id, name, bio FROM authors WHERE id = ? LIMIT 1", m.AuthorID)
+	var i Author
+	err := row.Scan(&i.ID, &i.Name, &i.Bio)
+	return i, err
+}
+
 
 type BookUpdate struct {
 	Id int64 `db:"id" json:"id" param:"id" query:"id" form:"id"`
@@ -42,5 +89,6 @@ type BookUpdate struct {
 func (m BookUpdate) TableName() string { return "books" }
 func (m BookUpdate) Columns() []string { return BookColumns }
 func (m BookUpdate) GetId() int64 { return m.Id }
+func (m BookUpdate) ValidationRules() map[string]string { return BookValidationRules }
 
 
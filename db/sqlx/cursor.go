@@ -0,0 +1,224 @@
+package sqlx
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// 在查询参数中标记游标
+const CursorKey = "cursor"
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor 游标分页位置,记录上一页最后一条记录的排序键和ID
+type Cursor struct {
+	Values []interface{} `json:"v,omitempty"` // 排序字段的值
+	Id     int64         `json:"id"`          // 记录ID,用于排序键相同时定位
+}
+
+// 与sqlx默认规则一致的字段映射,用于按列名读取结构体字段
+var columnMapper = reflectx.NewMapperFunc("db", strings.ToLower)
+
+// 游标中保存为字符串、比较前需还原为time.Time的字段类型
+var cursorTimeTypes = map[reflect.Type]struct{}{
+	reflect.TypeOf(time.Time{}):     {},
+	reflect.TypeOf(&time.Time{}):    {},
+	reflect.TypeOf(sql.NullTime{}):  {},
+	reflect.TypeOf(&sql.NullTime{}): {},
+}
+
+// EncodeCursor 将游标编码为不透明的字符串
+func EncodeCursor(cursor *Cursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor 解析游标字符串
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var cursor Cursor
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	// 还原数值类型,避免数字以字符串形式参与比较
+	for i, v := range cursor.Values {
+		if n, ok := v.(json.Number); ok {
+			if iv, err := n.Int64(); err == nil {
+				cursor.Values[i] = iv
+			} else if fv, err := n.Float64(); err == nil {
+				cursor.Values[i] = fv
+			}
+		}
+	}
+	return &cursor, nil
+}
+
+// NextCursor 根据当前页最后一条记录生成下一页游标
+func NextCursor(item ITable, filter *QueryFilter) (string, error) {
+	cursor := &Cursor{Id: item.GetId()}
+	for _, key := range filter.SortKeys() {
+		if key.Field == "id" {
+			continue
+		}
+		value, err := columnValue(item, key.Field)
+		if err != nil {
+			return "", err
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+	return EncodeCursor(cursor)
+}
+
+// columnValue 按列名读取记录中对应字段的值,时间统一以UTC的RFC3339Nano格式保存
+// 不使用columnMapper.FieldByName,它在字段不存在时返回结构体本身
+func columnValue(item ITable, column string) (interface{}, error) {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	fi, ok := columnMapper.TypeMap(v.Type()).Names[column]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidField, column)
+	}
+	field := v.FieldByIndex(fi.Index)
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return nil, nil
+		}
+		field = field.Elem()
+	}
+	value := field.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+	}
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC().Format(time.RFC3339Nano)
+	}
+	return value, nil
+}
+
+// isTimeColumn 判断列是否对应时间类型的字段
+func isTimeColumn(table ITable, column string) bool {
+	t := reflect.TypeOf(table)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	fi, ok := columnMapper.TypeMap(t).Names[column]
+	if !ok {
+		return false
+	}
+	_, ok = cursorTimeTypes[fi.Field.Type]
+	return ok
+}
+
+// cursorValue 还原游标中保存的排序字段值,时间列按RFC3339Nano解析为time.Time后绑定
+func cursorValue(table ITable, column string, value interface{}) (interface{}, error) {
+	if value == nil || !isTimeColumn(table, column) {
+		return value, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return t.UTC(), nil
+}
+
+// buildCursorClause 构建游标分页条件
+// 排序方向一致时使用行比较,如 (`sort_field`, `id`) > (?, ?)
+// 方向不一致时展开为 a > ? OR (a = ? AND b < ?) 的形式
+func buildCursorClause(d Dialect, table ITable, filter *QueryFilter) (string, []interface{}, error) {
+	cursor := filter.Cursor
+	if cursor == nil {
+		return "", nil, nil
+	}
+	keys, err := orderKeys(table, filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 按排序键顺序取出游标中的值,id使用游标记录的ID
+	values := make([]interface{}, len(keys))
+	next := 0
+	for i, key := range keys {
+		if key.Field == "id" {
+			values[i] = cursor.Id
+			continue
+		}
+		if next >= len(cursor.Values) {
+			return "", nil, ErrInvalidCursor
+		}
+		if values[i], err = cursorValue(table, key.Field, cursor.Values[next]); err != nil {
+			return "", nil, err
+		}
+		next++
+	}
+	if next != len(cursor.Values) {
+		return "", nil, ErrInvalidCursor
+	}
+
+	sameOrder := true
+	for _, key := range keys {
+		if key.Order != keys[0].Order {
+			sameOrder = false
+			break
+		}
+	}
+	if sameOrder {
+		operator := cursorOperator(keys[0].Order)
+		if len(keys) == 1 {
+			return d.Quote(keys[0].Field) + " " + operator + " ?", values, nil
+		}
+		columns := make([]string, len(keys))
+		placeholders := make([]string, len(keys))
+		for i, key := range keys {
+			columns[i] = d.Quote(key.Field)
+			placeholders[i] = "?"
+		}
+		clause := "(" + strings.Join(columns, ", ") + ") " + operator + " (" + strings.Join(placeholders, ", ") + ")"
+		return clause, values, nil
+	}
+
+	var terms []string
+	var args []interface{}
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, d.Quote(keys[j].Field)+" = ?")
+			args = append(args, values[j])
+		}
+		parts = append(parts, d.Quote(key.Field)+" "+cursorOperator(key.Order)+" ?")
+		args = append(args, values[i])
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args, nil
+}
+
+// cursorOperator 根据排序方向返回游标比较操作符
+func cursorOperator(order string) string {
+	if order == "DESC" {
+		return "<"
+	}
+	return ">"
+}
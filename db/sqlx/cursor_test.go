@@ -0,0 +1,221 @@
+package sqlx
+
+import (
+	sqlc "crud/db/sqlc"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// cursorEvent 包含时间列的测试表
+type cursorEvent struct {
+	ID        int64        `db:"id"`
+	StartedAt time.Time    `db:"started_at"`
+	EndedAt   sql.NullTime `db:"ended_at"`
+}
+
+var cursorEventColumns = []string{"id", "started_at", "ended_at"}
+
+func (m cursorEvent) TableName() string { return "events" }
+func (m cursorEvent) Columns() []string { return cursorEventColumns }
+func (m cursorEvent) ColumnsMap() map[string]struct{} {
+	return map[string]struct{}{"id": {}, "started_at": {}, "ended_at": {}}
+}
+func (m cursorEvent) GetId() int64 { return m.ID }
+
+func TestCursorEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor *Cursor
+		want   *Cursor
+	}{
+		{"id only", &Cursor{Id: 9}, &Cursor{Id: 9}},
+		{"string", &Cursor{Values: []interface{}{"tom"}, Id: 9}, &Cursor{Values: []interface{}{"tom"}, Id: 9}},
+		// 数值解码后还原为int64或float64,而不是json.Number或字符串
+		{"numbers", &Cursor{Values: []interface{}{3, 2.5, int64(1) << 60}, Id: 9}, &Cursor{Values: []interface{}{int64(3), 2.5, int64(1) << 60}, Id: 9}},
+		{"null", &Cursor{Values: []interface{}{nil, "a"}, Id: 1}, &Cursor{Values: []interface{}{nil, "a"}, Id: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := EncodeCursor(tt.cursor)
+			if err != nil {
+				t.Fatalf("EncodeCursor: %v", err)
+			}
+			got, err := DecodeCursor(encoded)
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	for _, value := range []string{"not base64!", base64.RawURLEncoding.EncodeToString([]byte("not json")), ""} {
+		if _, err := DecodeCursor(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) err = %v, want ErrInvalidCursor", value, err)
+		}
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	item := sqlc.Author{ID: 3, Name: "tom", Bio: sql.NullString{String: "cat", Valid: true}}
+	started := time.Date(2024, 5, 1, 8, 30, 0, 123456789, time.FixedZone("CST", 8*3600))
+	event := cursorEvent{ID: 5, StartedAt: started, EndedAt: sql.NullTime{Time: started, Valid: true}}
+	tests := []struct {
+		name   string
+		item   ITable
+		filter *QueryFilter
+		want   *Cursor
+	}{
+		{"no sort", item, &QueryFilter{}, &Cursor{Id: 3}},
+		{"id is not repeated in values", item, &QueryFilter{Sorts: []SortKey{{Field: "name"}, {Field: "id"}}}, &Cursor{Values: []interface{}{"tom"}, Id: 3}},
+		{"valuer", item, &QueryFilter{Sorts: []SortKey{{Field: "bio"}, {Field: "name"}}}, &Cursor{Values: []interface{}{"cat", "tom"}, Id: 3}},
+		{"null valuer", sqlc.Author{ID: 4, Name: "jerry"}, &QueryFilter{SortField: "bio"}, &Cursor{Values: []interface{}{nil}, Id: 4}},
+		// 时间以UTC的RFC3339Nano格式保存,不丢失时区和纳秒
+		{"time in utc", event, &QueryFilter{Sorts: []SortKey{{Field: "started_at"}, {Field: "ended_at"}}}, &Cursor{Values: []interface{}{"2024-05-01T00:30:00.123456789Z", "2024-05-01T00:30:00.123456789Z"}, Id: 5}},
+		{"null time", cursorEvent{ID: 6, StartedAt: started}, &QueryFilter{SortField: "ended_at"}, &Cursor{Values: []interface{}{nil}, Id: 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := NextCursor(tt.item, tt.filter)
+			if err != nil {
+				t.Fatalf("NextCursor: %v", err)
+			}
+			got, err := DecodeCursor(encoded)
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	// 未知的列不会读取到结构体本身
+	if _, err := columnValue(item, "password"); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("columnValue(password) err = %v, want ErrInvalidField", err)
+	}
+}
+
+func TestBuildCursorClauseTime(t *testing.T) {
+	started := time.Date(2024, 5, 1, 0, 30, 0, 123456789, time.UTC)
+	tests := []struct {
+		name     string
+		filter   *QueryFilter
+		wantArgs []interface{}
+		wantErr  error
+	}{
+		{
+			name:     "time is bound as time.Time",
+			filter:   &QueryFilter{SortField: "started_at", SortOrder: "ASC", Cursor: &Cursor{Values: []interface{}{"2024-05-01T08:30:00.123456789+08:00"}, Id: 7}},
+			wantArgs: []interface{}{started, int64(7)},
+		},
+		{
+			name:     "null time",
+			filter:   &QueryFilter{SortField: "ended_at", SortOrder: "ASC", Cursor: &Cursor{Values: []interface{}{nil}, Id: 7}},
+			wantArgs: []interface{}{nil, int64(7)},
+		},
+		{
+			name:    "malformed time",
+			filter:  &QueryFilter{SortField: "started_at", SortOrder: "ASC", Cursor: &Cursor{Values: []interface{}{"2024-05-01 00:30:00"}, Id: 7}},
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:    "number for a time column",
+			filter:  &QueryFilter{SortField: "ended_at", SortOrder: "ASC", Cursor: &Cursor{Values: []interface{}{int64(1)}, Id: 7}},
+			wantErr: ErrInvalidCursor,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, args, err := buildCursorClause(MySQL, cursorEvent{}, tt.filter)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Fatalf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBuildCursorClause(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		filter   *QueryFilter
+		want     string
+		wantArgs []interface{}
+		wantErr  error
+	}{
+		{
+			name:     "no cursor",
+			dialect:  MySQL,
+			filter:   &QueryFilter{SortField: "name", SortOrder: "ASC"},
+			want:     "",
+			wantArgs: nil,
+		},
+		{
+			name:     "id only",
+			dialect:  MySQL,
+			filter:   &QueryFilter{Cursor: &Cursor{Id: 7}},
+			want:     "`id` > ?",
+			wantArgs: []interface{}{int64(7)},
+		},
+		{
+			name:     "descending id",
+			dialect:  MySQL,
+			filter:   &QueryFilter{Sorts: []SortKey{{Field: "id", Order: "desc"}}, Cursor: &Cursor{Id: 7}},
+			want:     "`id` < ?",
+			wantArgs: []interface{}{int64(7)},
+		},
+		{
+			name:     "same direction uses a row comparison",
+			dialect:  MySQL,
+			filter:   &QueryFilter{Sorts: []SortKey{{Field: "name", Order: "DESC"}, {Field: "bio", Order: "DESC"}}, Cursor: &Cursor{Values: []interface{}{"tom", "cat"}, Id: 7}},
+			want:     "(`name`, `bio`, `id`) < (?, ?, ?)",
+			wantArgs: []interface{}{"tom", "cat", int64(7)},
+		},
+		{
+			name:     "mixed directions expand to or terms",
+			dialect:  MySQL,
+			filter:   &QueryFilter{Sorts: []SortKey{{Field: "name", Order: "DESC"}, {Field: "bio", Order: "ASC"}}, Cursor: &Cursor{Values: []interface{}{"tom", "cat"}, Id: 7}},
+			want:     "((`name` < ?) OR (`name` = ? AND `bio` > ?) OR (`name` = ? AND `bio` = ? AND `id` > ?))",
+			wantArgs: []interface{}{"tom", "tom", "cat", "tom", "cat", int64(7)},
+		},
+		{
+			name:     "explicit id in the middle",
+			dialect:  Postgres,
+			filter:   &QueryFilter{Sorts: []SortKey{{Field: "name", Order: "ASC"}, {Field: "id", Order: "DESC"}}, Cursor: &Cursor{Values: []interface{}{"tom"}, Id: 7}},
+			want:     `(("name" > ?) OR ("name" = ? AND "id" < ?))`,
+			wantArgs: []interface{}{"tom", "tom", int64(7)},
+		},
+		{
+			name:    "too few values",
+			dialect: MySQL,
+			filter:  &QueryFilter{Sorts: []SortKey{{Field: "name", Order: "ASC"}, {Field: "bio", Order: "ASC"}}, Cursor: &Cursor{Values: []interface{}{"tom"}, Id: 7}},
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:    "too many values",
+			dialect: MySQL,
+			filter:  &QueryFilter{Cursor: &Cursor{Values: []interface{}{"tom"}, Id: 7}},
+			wantErr: ErrInvalidCursor,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := buildCursorClause(tt.dialect, sqlc.Author{}, tt.filter)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Fatalf("got %q %v, want %q %v", got, args, tt.want, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package sqlx
+
+import (
+	pkgerrors "crud/pkg/errors"
+)
+
+// dbError 将可识别的MySQL、PostgreSQL驱动错误转换为业务错误(pkg/errors.Error),其他错误原样返回
+func dbError(err error) error {
+	if e := pkgerrors.FromDB(err); e != nil {
+		return e
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package sqlx
+
+import (
+	"strings"
+)
+
+// Dialect 屏蔽不同数据库之间的SQL语法差异,通用CRUD函数按连接的驱动名选择方言
+type Dialect interface {
+	Name() string                           // 方言名称,同时作为sqlx.NewDb的驱动名
+	Quote(identifier string) string         // 引用表名、列名等标识符
+	WriteLimit() bool                       // UPDATE/DELETE是否支持ORDER BY和LIMIT
+	MaxPlaceholders() int                   // 单条语句允许的最大占位符数量
+	FirstInsertId(lastId, rows int64) int64 // 根据多行INSERT的LastInsertId计算第一行的ID
+	OnConflictUpdate() string               // 冲突时更新的子句前缀
+	Excluded(column string) string          // 冲突更新时引用待插入值的表达式
+	Returning() bool                        // INSERT是否通过RETURNING返回生成的ID,为false时使用LastInsertId
+	Like() string                           // 不区分大小写的模糊匹配操作符
+}
+
+var dialects = map[string]Dialect{}
+
+func init() {
+	RegisterDialect(MySQL)
+	RegisterDialect(SQLite)
+	RegisterDialect(Postgres)
+}
+
+// RegisterDialect 注册方言,驱动名与方言名称相同的连接使用该方言
+func RegisterDialect(d Dialect) {
+	dialects[d.Name()] = d
+}
+
+// dialectOf 根据连接的驱动名获取方言,未注册的驱动按MySQL处理
+func dialectOf(db DBTX) Dialect {
+	if d, ok := dialects[db.DriverName()]; ok {
+		return d
+	}
+	return MySQL
+}
+
+// quoteWith 使用引号字符引用标识符,标识符中的引号字符加倍转义
+func quoteWith(quote string, identifier string) string {
+	return quote + strings.ReplaceAll(identifier, quote, quote+quote) + quote
+}
+
+// MySQL MySQL方言
+var MySQL Dialect = mysqlDialect{}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string                    { return "mysql" }
+func (mysqlDialect) Quote(identifier string) string  { return quoteWith("`", identifier) }
+func (mysqlDialect) WriteLimit() bool                { return true }
+func (mysqlDialect) MaxPlaceholders() int            { return 65535 }
+func (mysqlDialect) OnConflictUpdate() string        { return "ON DUPLICATE KEY UPDATE" }
+func (d mysqlDialect) Excluded(column string) string { return "VALUES(" + d.Quote(column) + ")" }
+func (mysqlDialect) Returning() bool                 { return false }
+func (mysqlDialect) Like() string                    { return "LIKE" }
+
+// FirstInsertId MySQL的LastInsertId即为多行INSERT第一行的ID
+// 其余行的ID只在innodb_autoinc_lock_mode为0或1时保证连续,见CreateMany_mysql
+func (mysqlDialect) FirstInsertId(lastId, rows int64) int64 { return lastId }
+
+// SQLite SQLite方言,使用纯Go实现的modernc.org/sqlite驱动,驱动名为sqlite
+var SQLite Dialect = sqliteDialect{}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string                    { return "sqlite" }
+func (sqliteDialect) Quote(identifier string) string  { return quoteWith(`"`, identifier) }
+func (sqliteDialect) WriteLimit() bool                { return false }
+func (sqliteDialect) MaxPlaceholders() int            { return 32766 }
+func (sqliteDialect) OnConflictUpdate() string        { return "ON CONFLICT DO UPDATE SET" }
+func (d sqliteDialect) Excluded(column string) string { return "excluded." + d.Quote(column) }
+func (sqliteDialect) Returning() bool                 { return false }
+func (sqliteDialect) Like() string                    { return "LIKE" }
+
+// FirstInsertId SQLite的last_insert_rowid为多行INSERT最后一行的ID
+func (sqliteDialect) FirstInsertId(lastId, rows int64) int64 { return lastId - rows + 1 }
+
+// Postgres PostgreSQL方言,驱动名为postgres(github.com/lib/pq)
+// 使用pgx等其他驱动时通过NewModelWithDialect指定方言,sqlx按方言名称将?改写为$n占位符
+var Postgres Dialect = postgresDialect{}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string                    { return "postgres" }
+func (postgresDialect) Quote(identifier string) string  { return quoteWith(`"`, identifier) }
+func (postgresDialect) WriteLimit() bool                { return false }
+func (postgresDialect) MaxPlaceholders() int            { return 65535 }
+func (d postgresDialect) Excluded(column string) string { return "excluded." + d.Quote(column) }
+func (postgresDialect) Returning() bool                 { return true }
+
+// OnConflictUpdate PostgreSQL的DO UPDATE必须指定冲突目标,按主键判断冲突
+func (d postgresDialect) OnConflictUpdate() string {
+	return "ON CONFLICT (" + d.Quote("id") + ") DO UPDATE SET"
+}
+
+// Like PostgreSQL的LIKE区分大小写,使用ILIKE与MySQL、SQLite的行为保持一致
+func (postgresDialect) Like() string { return "ILIKE" }
+
+// FirstInsertId PostgreSQL不支持LastInsertId,生成的ID通过RETURNING读取
+func (postgresDialect) FirstInsertId(lastId, rows int64) int64 { return lastId }
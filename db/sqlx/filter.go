@@ -2,7 +2,12 @@ package sqlx
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 var (
@@ -10,39 +15,97 @@ var (
 	ErrInvalidOperator = errors.New("invalid operator")
 	ErrInvalidLikeChar = errors.New("invalid characters in LIKE value")
 	ErrInvalidLikeType = errors.New("LIKE operator only supports string values")
+	ErrInvalidValue    = errors.New("invalid condition value")
+	ErrInvalidLogic    = errors.New("invalid group logic")
+	ErrGroupTooDeep    = errors.New("group nesting too deep")
+	ErrInvalidSort     = errors.New("invalid sort")
 )
 
-// CreateQuerySqlWithFilter 创建查询SQL语句
-func CreateQuerySqlWithFilter(table ITable, filter *QueryFilter) (string, []interface{}, error) {
-	query := buildBaseSelect(table.TableName())
+// CreateQuerySqlWithFilter 创建查询SQL语句,fieldFilter为nil时查询全部字段
+// 各Create*SqlWithFilter函数按关联过滤时通过tables查找关联表,tables为nil时不支持按关联过滤
+func CreateQuerySqlWithFilter(d Dialect, tables TableLookup, table ITable, filter *QueryFilter, fieldFilter *FieldFilter) (string, []interface{}, error) {
+	query, _, err := BuildSelectWithFieldFilter(d, table, fieldFilter)
+	if err != nil {
+		return "", nil, err
+	}
 	if filter == nil {
-		return query, nil, nil
+		filter = &QueryFilter{}
 	}
-	return combineConditions(table, query, nil, filter)
+	return combineConditions(d, tables, table, query, nil, filter)
 }
 
 // CreateUpdateSqlWithFilter 创建更新SQL语句
-func CreateUpdateSqlWithFilter(table ITable, tableUpdate ITableUpdate, filter *QueryFilter) (string, []interface{}, error) {
-	query, args, err := buildBaseUpdate(tableUpdate)
+func CreateUpdateSqlWithFilter(d Dialect, tables TableLookup, table ITable, tableUpdate ITableUpdate, filter *QueryFilter) (string, []interface{}, error) {
+	query, args, err := buildBaseUpdate(d, tableUpdate)
 	if err != nil {
 		return "", nil, err
 	}
 	if filter == nil {
-		return query, args, nil // 修复: 返回args而不是nil
+		filter = &QueryFilter{}
+	}
+	return combineWriteConditions(d, tables, table, query, args, filter)
+}
+
+// CreateDeleteSqlWithFilter 创建删除SQL语句,声明了软删除列的表生成写入删除时间的UPDATE语句
+func CreateDeleteSqlWithFilter(d Dialect, tables TableLookup, table ITable, filter *QueryFilter) (string, []interface{}, error) {
+	if filter == nil {
+		filter = &QueryFilter{}
 	}
-	return combineConditions(table, query, args, filter)
+	if column, ok := softDeleteColumn(table); ok {
+		scoped := *filter
+		scoped.Deleted = ExcludeDeleted
+		query := fmt.Sprintf("UPDATE %s SET %s = ?", d.Quote(table.TableName()), d.Quote(column))
+		return combineWriteConditions(d, tables, table, query, []interface{}{time.Now()}, &scoped)
+	}
+	return combineWriteConditions(d, tables, table, buildBaseDelete(d, table.TableName()), nil, filter)
+}
+
+// CreatePurgeSqlWithFilter 创建物理删除已软删除记录的SQL语句
+func CreatePurgeSqlWithFilter(d Dialect, tables TableLookup, table ITable, filter *QueryFilter) (string, []interface{}, error) {
+	if _, ok := softDeleteColumn(table); !ok {
+		return "", nil, ErrSoftDeleteUnsupported
+	}
+	scoped := QueryFilter{}
+	if filter != nil {
+		scoped = *filter
+	}
+	scoped.Deleted = OnlyDeleted
+	return combineWriteConditions(d, tables, table, buildBaseDelete(d, table.TableName()), nil, &scoped)
 }
 
-// CreateDeleteSqlWithFilter 创建删除SQL语句
-func CreateDeleteSqlWithFilter(table ITable, filter *QueryFilter) (string, []interface{}, error) {
-	query := buildBaseDelete(table.TableName())
+// CreateCountSqlWithFilter 创建统计SQL语句,只使用过滤条件,忽略排序和分页
+func CreateCountSqlWithFilter(d Dialect, tables TableLookup, table ITable, filter *QueryFilter) (string, []interface{}, error) {
+	query := buildBaseCount(d, table.TableName())
 	if filter == nil {
-		return query, nil, nil
+		filter = &QueryFilter{}
+	}
+	where, args, err := buildWhereClause(d, tables, table, filter)
+	if err != nil {
+		return "", nil, err
 	}
-	return combineConditions(table, query, nil, filter)
+	if where != "" {
+		query += " WHERE " + where
+	}
+	return query, args, nil
+}
+
+// combineWriteConditions 为UPDATE/DELETE语句追加过滤条件
+// 方言不支持UPDATE/DELETE的ORDER BY和LIMIT时,改为按子查询选出的id写入
+func combineWriteConditions(d Dialect, tables TableLookup, table ITable, query string, args []interface{}, filter *QueryFilter) (string, []interface{}, error) {
+	ordered := filter.Limit != 0 || filter.Offset != 0 || filter.Cursor != nil || len(filter.SortKeys()) > 0
+	if d.WriteLimit() || !ordered {
+		return combineConditions(d, tables, table, query, args, filter)
+	}
+	id := d.Quote("id")
+	subQuery := fmt.Sprintf("SELECT %s FROM %s", id, d.Quote(table.TableName()))
+	subQuery, subArgs, err := combineConditions(d, tables, table, subQuery, nil, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	return query + " WHERE " + id + " IN (" + subQuery + ")", append(args, subArgs...), nil
 }
 
-func combineConditions(table ITable, query string, args []interface{}, filter *QueryFilter) (string, []interface{}, error) {
+func combineConditions(d Dialect, tables TableLookup, table ITable, query string, args []interface{}, filter *QueryFilter) (string, []interface{}, error) {
 	if args == nil {
 		args = make([]interface{}, 0) // 修复: 初始化args避免nil
 	}
@@ -51,90 +114,293 @@ func combineConditions(table ITable, query string, args []interface{}, filter *Q
 	builder.WriteString(query)
 
 	// 处理查询条件
-	for i, condition := range filter.Conditions {
-		if condition == nil {
-			continue
+	where, whereArgs, err := buildWhereClause(d, tables, table, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	// 游标分页条件,顶层条件以AND连接,可直接追加
+	cursorClause, cursorArgs, err := buildCursorClause(d, table, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	if cursorClause != "" {
+		if where != "" {
+			where += " AND "
 		}
+		where += cursorClause
+		whereArgs = append(whereArgs, cursorArgs...)
+	}
+	if where != "" {
+		builder.WriteString(" WHERE ")
+		builder.WriteString(where)
+		args = append(args, whereArgs...)
+	}
+
+	// 验证排序参数
+	orderBy, err := buildOrderBy(d, table, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	builder.WriteString(orderBy)
+
+	if filter.Limit != 0 {
+		builder.WriteString(" LIMIT ?")
+		args = append(args, filter.Limit)
+	}
+	if filter.Offset != 0 && filter.Cursor == nil {
+		builder.WriteString(" OFFSET ?")
+		args = append(args, filter.Offset)
+	}
+	return builder.String(), args, nil
+}
+
+// buildOrderBy 构建ORDER BY子句,游标分页时追加id作为次级排序
+func buildOrderBy(d Dialect, table ITable, filter *QueryFilter) (string, error) {
+	keys, err := orderKeys(table, filter)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = d.Quote(key.Field) + " " + key.Order
+	}
+	return " ORDER BY " + strings.Join(parts, ", "), nil
+}
+
+// orderKeys 校验并返回排序键列表
+func orderKeys(table ITable, filter *QueryFilter) ([]SortKey, error) {
+	sortKeys := filter.SortKeys()
+	if len(sortKeys) > maxSortKeys {
+		return nil, fmt.Errorf("%w: too many sort fields, max %d", ErrInvalidSort, maxSortKeys)
+	}
+	keys := make([]SortKey, 0, len(sortKeys)+1)
+	hasId := false
+	for _, key := range sortKeys {
 		// 防止SQL注入,验证字段名是否在白名单中
-		if _, ok := table.ColumnsMap()[condition.Field]; !ok {
-			return "", nil, ErrInvalidField
+		if _, ok := table.ColumnsMap()[key.Field]; !ok {
+			return nil, fmt.Errorf("%w field: %s", ErrInvalidSort, key.Field)
 		}
-		// 添加字段名长度限制
-		if len(condition.Field) == 0 || len(condition.Field) > 64 { // 修复: 检查空字段名
-			return "", nil, errors.New("invalid field name length")
+		// 验证排序方向
+		upperOrder := strings.ToUpper(key.Order)
+		if upperOrder != "ASC" && upperOrder != "DESC" {
+			return nil, fmt.Errorf("%w order: %s", ErrInvalidSort, key.Order)
 		}
-		// 验证操作符
-		if condition.Operator == "" { // 修复: 检查空操作符
-			return "", nil, ErrInvalidOperator
+		if key.Field == "id" {
+			hasId = true
 		}
-		upperOperator := strings.ToUpper(condition.Operator)
-		if !allowedOperators[upperOperator] {
-			return "", nil, ErrInvalidOperator
+		keys = append(keys, SortKey{Field: key.Field, Order: upperOrder})
+	}
+	// 游标分页需要以id作为最后的排序键保证顺序稳定,方向与最后一个排序键一致
+	if filter.Cursor != nil && !hasId {
+		order := "ASC"
+		if len(keys) > 0 {
+			order = keys[len(keys)-1].Order
 		}
-		condition.Operator = upperOperator
+		keys = append(keys, SortKey{Field: "id", Order: order})
+	}
+	return keys, nil
+}
 
-		// 验证 LIKE 操作符的值
-		if condition.Operator == "LIKE" {
-			if condition.Value == nil { // 修复: 检查nil值
-				return "", nil, ErrInvalidLikeType
-			}
-			if strValue, ok := condition.Value.(string); ok {
-				// 更严格的字符检查
-				if strings.ContainsAny(strValue, "%_\\'\"`;") || len(strValue) > 100 {
-					return "", nil, ErrInvalidLikeChar
-				}
-				// 添加通配符限制
-				if strings.Count(strValue, "%") > 2 {
-					return "", nil, errors.New("too many wildcards in LIKE pattern")
-				}
-				condition.Value = strValue
-			} else {
-				return "", nil, ErrInvalidLikeType
-			}
+// buildWhereClause 构建WHERE子句(不含WHERE关键字),顶层条件与分组之间以AND连接
+// 声明了软删除列的表会按filter.Deleted追加删除时间条件
+func buildWhereClause(d Dialect, tables TableLookup, table ITable, filter *QueryFilter) (string, []interface{}, error) {
+	root := &QueryGroup{
+		Logic:      "AND",
+		Conditions: filter.Conditions,
+		Groups:     filter.Groups,
+	}
+	where, args, err := buildGroup(d, tables, table, root, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	if scope := softDeleteClause(d, table, filter.Deleted); scope != "" {
+		if where != "" {
+			where += " AND "
 		}
+		where += scope
+	}
+	return where, args, nil
+}
 
-		if i == 0 {
-			builder.WriteString(" WHERE ")
-		} else {
-			builder.WriteString(" AND ")
+// buildGroup 递归构建条件分组,子分组使用括号包裹
+func buildGroup(d Dialect, tables TableLookup, table ITable, group *QueryGroup, depth int) (string, []interface{}, error) {
+	if depth > maxGroupDepth {
+		return "", nil, ErrGroupTooDeep
+	}
+	logic := strings.ToUpper(group.Logic)
+	if logic == "" {
+		logic = "AND"
+	}
+	if logic != "AND" && logic != "OR" {
+		return "", nil, ErrInvalidLogic
+	}
+
+	var parts []string
+	var args []interface{}
+	for _, condition := range group.Conditions {
+		if condition == nil {
+			continue
+		}
+		part, partArgs, err := buildCondition(d, tables, table, condition)
+		if err != nil {
+			return "", nil, err
 		}
+		parts = append(parts, part)
+		args = append(args, partArgs...)
+	}
+	for _, sub := range group.Groups {
+		if sub == nil {
+			continue
+		}
+		part, partArgs, err := buildGroup(d, tables, table, sub, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		if part == "" {
+			continue
+		}
+		parts = append(parts, "("+part+")")
+		args = append(args, partArgs...)
+	}
+	if len(parts) == 0 {
+		return "", nil, nil
+	}
 
-		// 使用引号包裹字段名,防止SQL注入
-		builder.WriteString("`")
-		builder.WriteString(condition.Field)
-		builder.WriteString("`")
-		builder.WriteString(" ")
-		builder.WriteString(condition.Operator)
-		builder.WriteString(" ?")
-		args = append(args, condition.Value)
+	clause := strings.Join(parts, " "+logic+" ")
+	if group.Not {
+		clause = "NOT (" + clause + ")"
 	}
+	return clause, args, nil
+}
 
-	// 验证排序参数
-	if filter.SortField != "" {
-		// 防止SQL注入,验证字段名是否在白名单中
-		if _, ok := table.ColumnsMap()[filter.SortField]; !ok {
-			return "", nil, errors.New("invalid sort field")
+// buildCondition 校验并构建单个过滤条件
+func buildCondition(d Dialect, tables TableLookup, table ITable, condition *QueryCondition) (string, []interface{}, error) {
+	// 关联名称.列名 按关联表的列过滤
+	if strings.Contains(condition.Field, ".") {
+		return buildRelationCondition(d, tables, table, condition)
+	}
+	// 防止SQL注入,验证字段名是否在白名单中
+	if _, ok := table.ColumnsMap()[condition.Field]; !ok {
+		return "", nil, ErrInvalidField
+	}
+	// 添加字段名长度限制
+	if len(condition.Field) == 0 || len(condition.Field) > 64 { // 修复: 检查空字段名
+		return "", nil, ErrInvalidField
+	}
+	// 验证操作符
+	if condition.Operator == "" { // 修复: 检查空操作符
+		return "", nil, ErrInvalidOperator
+	}
+	upperOperator := strings.ToUpper(condition.Operator)
+	if !allowedOperators[upperOperator] {
+		return "", nil, ErrInvalidOperator
+	}
+	condition.Operator = upperOperator
+
+	// 验证 LIKE 操作符的值
+	if condition.Operator == "LIKE" {
+		if condition.Value == nil { // 修复: 检查nil值
+			return "", nil, ErrInvalidLikeType
 		}
+		if strValue, ok := condition.Value.(string); ok {
+			// 更严格的字符检查
+			if strings.ContainsAny(strValue, "%_\\'\"`;") || len(strValue) > 100 {
+				return "", nil, ErrInvalidLikeChar
+			}
+			// 添加通配符限制
+			if strings.Count(strValue, "%") > 2 {
+				return "", nil, ErrInvalidLikeChar
+			}
+			condition.Value = strValue
+		} else {
+			return "", nil, ErrInvalidLikeType
+		}
+	}
 
-		// 验证排序方向
-		upperOrder := strings.ToUpper(filter.SortOrder)
-		if upperOrder != "ASC" && upperOrder != "DESC" {
-			return "", nil, errors.New("invalid sort order")
+	// 使用引号包裹字段名,防止SQL注入
+	column := d.Quote(condition.Field)
+	switch condition.Operator {
+	case "IS NULL", "IS NOT NULL":
+		return column + " " + condition.Operator, nil, nil
+	case "IN", "NOT IN":
+		values, err := conditionValues(condition)
+		if err != nil {
+			return "", nil, err
+		}
+		// 使用sqlx.In展开占位符
+		return sqlx.In(column+" "+condition.Operator+" (?)", values)
+	case "BETWEEN":
+		values, err := conditionValues(condition)
+		if err != nil {
+			return "", nil, err
 		}
+		return column + " BETWEEN ? AND ?", values, nil
+	case "LIKE":
+		// 按方言使用不区分大小写的模糊匹配
+		return column + " " + d.Like() + " ?", []interface{}{condition.Value}, nil
+	}
+	if condition.Value == nil {
+		return "", nil, ErrInvalidValue
+	}
+	return column + " " + condition.Operator + " ?", []interface{}{condition.Value}, nil
+}
 
-		builder.WriteString(" ORDER BY `")
-		builder.WriteString(filter.SortField)
-		builder.WriteString("` ")
-		builder.WriteString(upperOrder)
+// buildRelationCondition 构建按关联表的列过滤的条件,字段格式为 关联名称.列名,如 author.name_like=tom
+// belongs_to:   author_id IN (SELECT id FROM authors WHERE name LIKE ?)
+// has_many:     id IN (SELECT author_id FROM books WHERE title = ?)
+// many_to_many: id IN (SELECT book_id FROM book_tags WHERE tag_id IN (SELECT id FROM tags WHERE name = ?))
+// 关联表需能通过tables查找,否则返回错误
+func buildRelationCondition(d Dialect, tables TableLookup, table ITable, condition *QueryCondition) (string, []interface{}, error) {
+	name, field, _ := strings.Cut(condition.Field, ".")
+	relations, err := RelationsOf(table)
+	if err != nil {
+		return "", nil, err
+	}
+	relation, ok := relations[name]
+	if !ok {
+		return "", nil, ErrInvalidField
 	}
+	if tables == nil {
+		return "", nil, fmt.Errorf("table %s is not registered", relation.Table)
+	}
+	related, err := tables(relation.Table)
+	if err != nil {
+		return "", nil, err
+	}
+	where, args, err := buildCondition(d, tables, related, &QueryCondition{Field: field, Value: condition.Value, Operator: condition.Operator})
+	if err != nil {
+		return "", nil, err
+	}
+	if scope := softDeleteClause(d, related, ExcludeDeleted); scope != "" {
+		where += " AND " + scope
+	}
+	id, relatedTable := d.Quote("id"), d.Quote(related.TableName())
+	switch relation.Kind {
+	case BelongsTo:
+		return fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s)", d.Quote(relation.ForeignKey), id, relatedTable, where), args, nil
+	case HasMany:
+		return fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s)", id, d.Quote(relation.ForeignKey), relatedTable, where), args, nil
+	default:
+		return fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s IN (SELECT %s FROM %s WHERE %s))", id, d.Quote(relation.ForeignKey),
+			d.Quote(relation.Through), d.Quote(relation.References), id, relatedTable, where), args, nil
+	}
+}
 
-	if filter.Limit != 0 {
-		builder.WriteString(" LIMIT ?")
-		args = append(args, filter.Limit)
+// conditionValues 将列表类条件的值展开为参数列表并校验个数
+func conditionValues(condition *QueryCondition) ([]interface{}, error) {
+	v := reflect.ValueOf(condition.Value)
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, ErrInvalidValue
 	}
-	if filter.Offset != 0 {
-		builder.WriteString(" OFFSET ?")
-		args = append(args, filter.Offset)
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
 	}
-	return builder.String(), args, nil
+	if err := checkOperatorArity(condition.Operator, len(values)); err != nil {
+		return nil, err
+	}
+	return values, nil
 }
@@ -0,0 +1,83 @@
+package sqlx
+
+import (
+	sqlc "crud/db/sqlc"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCombineWriteConditions(t *testing.T) {
+	byName := []*QueryCondition{{Field: "name", Value: "tom", Operator: "="}}
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		query    string
+		args     []interface{}
+		filter   *QueryFilter
+		want     string
+		wantArgs []interface{}
+		wantErr  error
+	}{
+		{
+			name:     "no conditions",
+			dialect:  SQLite,
+			query:    `DELETE FROM "authors"`,
+			filter:   &QueryFilter{},
+			want:     `DELETE FROM "authors"`,
+			wantArgs: []interface{}{},
+		},
+		{
+			name:     "conditions only",
+			dialect:  SQLite,
+			query:    `DELETE FROM "authors"`,
+			filter:   &QueryFilter{Conditions: byName},
+			want:     `DELETE FROM "authors" WHERE "name" = ?`,
+			wantArgs: []interface{}{"tom"},
+		},
+		{
+			name:     "mysql writes order by and limit directly",
+			dialect:  MySQL,
+			query:    "UPDATE `authors` SET `bio` = ?",
+			args:     []interface{}{"cat"},
+			filter:   &QueryFilter{Conditions: byName, SortField: "id", SortOrder: "desc", Limit: 2},
+			want:     "UPDATE `authors` SET `bio` = ? WHERE `name` = ? ORDER BY `id` DESC LIMIT ?",
+			wantArgs: []interface{}{"cat", "tom", 2},
+		},
+		{
+			name:     "sqlite limit uses an id subquery",
+			dialect:  SQLite,
+			query:    `DELETE FROM "authors"`,
+			filter:   &QueryFilter{Conditions: byName, Limit: 2},
+			want:     `DELETE FROM "authors" WHERE "id" IN (SELECT "id" FROM "authors" WHERE "name" = ? LIMIT ?)`,
+			wantArgs: []interface{}{"tom", 2},
+		},
+		{
+			name:     "postgres sort keeps the set arguments first",
+			dialect:  Postgres,
+			query:    `UPDATE "authors" SET "bio" = ?`,
+			args:     []interface{}{"cat"},
+			filter:   &QueryFilter{Conditions: byName, Sorts: []SortKey{{Field: "name", Order: "ASC"}}, Limit: 1, Offset: 3},
+			want:     `UPDATE "authors" SET "bio" = ? WHERE "id" IN (SELECT "id" FROM "authors" WHERE "name" = ? ORDER BY "name" ASC LIMIT ? OFFSET ?)`,
+			wantArgs: []interface{}{"cat", "tom", 1, 3},
+		},
+		{
+			name:    "invalid field",
+			dialect: SQLite,
+			query:   `DELETE FROM "authors"`,
+			filter:  &QueryFilter{Conditions: []*QueryCondition{{Field: "password", Value: "x", Operator: "="}}, Limit: 1},
+			wantErr: ErrInvalidField,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := combineWriteConditions(tt.dialect, nil, sqlc.Author{}, tt.query, tt.args, tt.filter)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Fatalf("got %q %v, want %q %v", got, args, tt.want, tt.wantArgs)
+			}
+		})
+	}
+}
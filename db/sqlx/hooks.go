@@ -0,0 +1,160 @@
+package sqlx
+
+import (
+	"context"
+	"reflect"
+)
+
+// 模型可选实现的生命周期钩子,Table[T]在T(查询、创建、删除)或XxxUpdate(更新)上检测
+// 需要修改记录的钩子应使用指针接收者
+// 写操作钩子在事务中执行,模型已绑定事务时使用保存点,钩子返回错误时整个操作回滚
+
+// IBeforeCreate 插入前调用,可用于规范化字段,upsert同样会调用
+type IBeforeCreate interface {
+	BeforeCreate(ctx context.Context, tx *Tx) error
+}
+
+// IAfterCreate 插入后调用,记录已包含生成的ID
+type IAfterCreate interface {
+	AfterCreate(ctx context.Context, tx *Tx) error
+}
+
+// IBeforeUpdate 更新前在XxxUpdate上调用
+type IBeforeUpdate interface {
+	BeforeUpdate(ctx context.Context, tx *Tx) error
+}
+
+// IAfterUpdate 更新后在XxxUpdate上调用,没有记录被更新时不调用
+type IAfterUpdate interface {
+	AfterUpdate(ctx context.Context, tx *Tx) error
+}
+
+// IBeforeDelete 删除前在事务中查出的每条待删除记录上调用,不存在的ID不会调用
+type IBeforeDelete interface {
+	BeforeDelete(ctx context.Context, tx *Tx) error
+}
+
+// IAfterDelete 删除后在每条已删除记录上调用,记录为删除前查出的内容,可用于级联清理,没有记录被删除时不调用
+type IAfterDelete interface {
+	AfterDelete(ctx context.Context, tx *Tx) error
+}
+
+// IAfterFind 查询到记录后对每条记录调用
+type IAfterFind interface {
+	AfterFind(ctx context.Context) error
+}
+
+// hasCreateHooks 判断T是否实现了创建钩子
+func hasCreateHooks[T ITable]() bool {
+	var item T
+	_, before := any(&item).(IBeforeCreate)
+	_, after := any(&item).(IAfterCreate)
+	return before || after
+}
+
+// hasDeleteHooks 判断T是否实现了删除钩子
+func hasDeleteHooks[T ITable]() bool {
+	var item T
+	_, before := any(&item).(IBeforeDelete)
+	_, after := any(&item).(IAfterDelete)
+	return before || after
+}
+
+// hasUpdateHooks 判断更新内容是否实现了更新钩子,payload为updatePayload返回的指针
+func hasUpdateHooks(payload any) bool {
+	_, before := payload.(IBeforeUpdate)
+	_, after := payload.(IAfterUpdate)
+	return before || after
+}
+
+// updatePayload 复制更新内容并返回其指针,使指针接收者的钩子可以修改更新内容
+func updatePayload(table ITableUpdate) any {
+	v := reflect.ValueOf(table)
+	if v.Kind() == reflect.Pointer {
+		return table
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr.Interface()
+}
+
+// updateValue 取出updatePayload指针指向的更新内容
+func updateValue(payload any) ITableUpdate {
+	return reflect.ValueOf(payload).Elem().Interface().(ITableUpdate)
+}
+
+func beforeCreate(ctx context.Context, tx *Tx, item any) error {
+	if hook, ok := item.(IBeforeCreate); ok {
+		return hook.BeforeCreate(ctx, tx)
+	}
+	return nil
+}
+
+func afterCreate(ctx context.Context, tx *Tx, item any) error {
+	if hook, ok := item.(IAfterCreate); ok {
+		return hook.AfterCreate(ctx, tx)
+	}
+	return nil
+}
+
+func beforeUpdate(ctx context.Context, tx *Tx, payload any) error {
+	if hook, ok := payload.(IBeforeUpdate); ok {
+		return hook.BeforeUpdate(ctx, tx)
+	}
+	return nil
+}
+
+func afterUpdate(ctx context.Context, tx *Tx, payload any) error {
+	if hook, ok := payload.(IAfterUpdate); ok {
+		return hook.AfterUpdate(ctx, tx)
+	}
+	return nil
+}
+
+// beforeDelete 对待删除的记录逐条调用BeforeDelete钩子
+func beforeDelete[T ITable](ctx context.Context, tx *Tx, items []T) error {
+	for i := range items {
+		if hook, ok := any(&items[i]).(IBeforeDelete); ok {
+			if err := hook.BeforeDelete(ctx, tx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterDelete 对已删除的记录逐条调用AfterDelete钩子
+func afterDelete[T ITable](ctx context.Context, tx *Tx, items []T) error {
+	for i := range items {
+		if hook, ok := any(&items[i]).(IAfterDelete); ok {
+			if err := hook.AfterDelete(ctx, tx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterFind 对查询结果逐条调用AfterFind钩子
+func afterFind[T ITable](ctx context.Context, items []T) error {
+	for i := range items {
+		if hook, ok := any(&items[i]).(IAfterFind); ok {
+			if err := hook.AfterFind(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setId 将生成的ID写回记录,item必须为指针
+func setId(item any, id int64) {
+	v := reflect.ValueOf(item).Elem()
+	fi, ok := columnMapper.TypeMap(v.Type()).Names["id"]
+	if !ok {
+		return
+	}
+	if field := v.FieldByIndex(fi.Index); field.CanSet() && field.CanInt() {
+		field.SetInt(id)
+	}
+}
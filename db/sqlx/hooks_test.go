@@ -0,0 +1,228 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hookedPost 实现删除钩子的测试表,钩子记录收到的记录内容
+type hookedPost struct {
+	ID    int64  `db:"id" json:"id"`
+	Title string `db:"title" json:"title"`
+}
+
+var (
+	hookedPostColumns    = []string{"id", "title"}
+	hookedPostColumnsMap = map[string]struct{}{"id": {}, "title": {}}
+
+	deletedPosts []string // BeforeDelete收到的标题
+	cleanedPosts []string // AfterDelete收到的标题
+	errLocked    = errors.New("post is locked")
+)
+
+func (m hookedPost) TableName() string               { return "posts" }
+func (m hookedPost) Columns() []string               { return hookedPostColumns }
+func (m hookedPost) ColumnsMap() map[string]struct{} { return hookedPostColumnsMap }
+func (m hookedPost) GetId() int64                    { return m.ID }
+
+func (m *hookedPost) BeforeDelete(ctx context.Context, tx *Tx) error {
+	if m.Title == "locked" {
+		return errLocked
+	}
+	deletedPosts = append(deletedPosts, m.Title)
+	return nil
+}
+
+func (m *hookedPost) AfterDelete(ctx context.Context, tx *Tx) error {
+	cleanedPosts = append(cleanedPosts, m.Title)
+	return nil
+}
+
+func TestDeleteHooks(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	if _, err := repo.DB().Exec(`CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL)`); err != nil {
+		t.Fatalf("create posts: %v", err)
+	}
+	posts := Model[hookedPost](repo)
+	ids, err := posts.CreateMany(ctx, []hookedPost{{Title: "a"}, {Title: "b"}, {Title: "c"}, {Title: "locked"}})
+	if err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		delete      func() (int64, error)
+		wantErr     error
+		wantDeleted int64
+		wantBefore  []string
+		wantAfter   []string
+	}{
+		{
+			name:        "hooks receive the loaded record",
+			delete:      func() (int64, error) { return posts.DeleteOne(ctx, ids[0]) },
+			wantDeleted: 1,
+			wantBefore:  []string{"a"},
+			wantAfter:   []string{"a"},
+		},
+		{
+			// 不存在的ID不会以零值记录调用钩子
+			name:   "missing id does not call hooks",
+			delete: func() (int64, error) { return posts.DeleteOne(ctx, ids[0]) },
+		},
+		{
+			name: "filter",
+			delete: func() (int64, error) {
+				return posts.DeleteSomeByFilter(ctx, &QueryFilter{Conditions: []*QueryCondition{{Field: "title", Value: "b", Operator: "="}}})
+			},
+			wantDeleted: 1,
+			wantBefore:  []string{"b"},
+			wantAfter:   []string{"b"},
+		},
+		{
+			name:       "before hook error rolls back",
+			delete:     func() (int64, error) { return posts.DeleteSomeByIds(ctx, ids[2:]) },
+			wantErr:    errLocked,
+			wantBefore: []string{"c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deletedPosts, cleanedPosts = nil, nil
+			deleted, err := tt.delete()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("deleted = %d, want %d", deleted, tt.wantDeleted)
+			}
+			if !reflect.DeepEqual(deletedPosts, tt.wantBefore) || !reflect.DeepEqual(cleanedPosts, tt.wantAfter) {
+				t.Errorf("hooks = %v %v, want %v %v", deletedPosts, cleanedPosts, tt.wantBefore, tt.wantAfter)
+			}
+		})
+	}
+
+	remaining, err := posts.FindSomeByIds(ctx, ids, nil)
+	if err != nil {
+		t.Fatalf("FindSomeByIds: %v", err)
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("remaining = %+v, want c and locked", remaining)
+	}
+}
+
+// sluggedPost 由BeforeCreate钩子生成必填字段的测试表
+type sluggedPost struct {
+	ID    int64  `db:"id" json:"id"`
+	Title string `db:"title" json:"title" validate:"required"`
+	Slug  string `db:"slug" json:"slug" validate:"required,regex=^[a-z-]+$"`
+}
+
+func (m sluggedPost) TableName() string { return "slugged_posts" }
+func (m sluggedPost) Columns() []string { return []string{"id", "title", "slug"} }
+func (m sluggedPost) ColumnsMap() map[string]struct{} {
+	return map[string]struct{}{"id": {}, "title": {}, "slug": {}}
+}
+func (m sluggedPost) GetId() int64 { return m.ID }
+
+func (m *sluggedPost) BeforeCreate(ctx context.Context, tx *Tx) error {
+	m.Slug = strings.ToLower(strings.ReplaceAll(m.Title, " ", "-"))
+	return nil
+}
+
+// failedRules 返回未通过的 字段:规则,以逗号分隔,其他错误返回错误信息
+func failedRules(err error) string {
+	var fieldErrs ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return errorText(err)
+	}
+	rules := make([]string, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		rules[i] = fieldErr.Field + ":" + fieldErr.Rule
+	}
+	return strings.Join(rules, ",")
+}
+
+func TestValidateAfterBeforeHooks(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	if _, err := repo.DB().Exec(`CREATE TABLE slugged_posts (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL, slug TEXT NOT NULL)`); err != nil {
+		t.Fatalf("create slugged_posts: %v", err)
+	}
+	plain := Model[sluggedPost](repo)
+	posts := plain.WithValidation()
+
+	// 钩子执行前slug为空,单独校验无法通过
+	if err := plain.Validate(ctx, sluggedPost{Title: "Hello World"}); failedRules(err) != "slug:required" {
+		t.Fatalf("Validate = %v, want slug:required", err)
+	}
+
+	tests := []struct {
+		name    string
+		create  func() error
+		wantErr string
+	}{
+		{
+			name: "create one",
+			create: func() error {
+				_, err := posts.CreateOne(ctx, sluggedPost{Title: "Hello World"})
+				return err
+			},
+		},
+		{
+			name: "create many",
+			create: func() error {
+				_, err := posts.CreateMany(ctx, []sluggedPost{{Title: "a"}, {Title: "b"}})
+				return err
+			},
+		},
+		{
+			// 钩子生成的值同样经过校验
+			name: "hook output is validated",
+			create: func() error {
+				_, err := posts.CreateOne(ctx, sluggedPost{Title: "Go 1.22"})
+				return err
+			},
+			wantErr: "slug:regex",
+		},
+		{
+			// 任意一条未通过校验时整批回滚
+			name: "create many rolls back",
+			create: func() error {
+				_, err := posts.CreateMany(ctx, []sluggedPost{{Title: "c"}, {}})
+				return err
+			},
+			wantErr: "[1].title:required,[1].slug:required",
+		},
+		{
+			name: "upsert",
+			create: func() error {
+				_, err := posts.UpsertOne(ctx, sluggedPost{}, nil)
+				return err
+			},
+			wantErr: "[0].title:required,[0].slug:required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failedRules(tt.create()); got != tt.wantErr {
+				t.Fatalf("err = %q, want %q", got, tt.wantErr)
+			}
+		})
+	}
+
+	items, err := posts.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	var slugs []string
+	for _, item := range items {
+		slugs = append(slugs, item.Slug)
+	}
+	if want := []string{"hello-world", "a", "b"}; !reflect.DeepEqual(slugs, want) {
+		t.Fatalf("slugs = %v, want %v", slugs, want)
+	}
+}
@@ -2,49 +2,57 @@ package sqlx
 
 import (
 	"context"
-	sqlc "crud/db/sqlc"
 	"database/sql"
-
-	"github.com/jmoiron/sqlx"
+	"fmt"
 )
 
 // Table 通用数据库表操作封装
 type Table[T ITable] struct {
-	table T             // 表结构实例
-	db    *sqlx.DB      // sqlx数据库连接
-	Sqlc  *sqlc.Queries // sqlc查询实例
+	table    T           // 表结构实例
+	repo     *Repository // 执行查询的数据库句柄,可能绑定到事务
+	validate bool        // 写入前是否按校验规则校验,见WithValidation
+	Sqlc     any         // sqlc查询实例,类型见Repository.Sqlc
 }
 
-// NewModel 创建新的数据库操作模型
+// NewModel 创建新的数据库操作模型,使用MySQL方言
+// 多个表共享同一连接时应通过NewRepository创建句柄,再使用Model派生各表的模型
 func NewModel[T ITable](db *sql.DB) *Table[T] {
-	dbx := sqlx.NewDb(db, "mysql")
-	var tableInstance T
-	return &Table[T]{
-		table: tableInstance,
-		db:    dbx,
-		Sqlc:  sqlc.New(db),
-	}
+	return NewModelWithDialect[T](db, MySQL)
 }
 
-// NewModelWithGlobal 使用共享的sqlc/sqlx实例创建新的数据库操作模型
-func NewModelWithGlobal[T ITable](db *sql.DB) *Table[T] {
-	var tableInstance T
-	if _sqlc == nil {
-		InitSqlc(_db.DB)
-	}
-	if _db == nil {
-		InitSqlx(db)
-	}
-	return &Table[T]{
-		table: tableInstance,
-		db:    _db,
-		Sqlc:  _sqlc,
-	}
+// NewModelWithDialect 创建使用指定方言的数据库操作模型,例如在测试中使用SQLite
+func NewModelWithDialect[T ITable](db *sql.DB, d Dialect) *Table[T] {
+	return Model[T](NewRepository(db, d))
+}
+
+// InTx 返回绑定到指定事务的模型副本
+func (m *Table[T]) InTx(tx *Tx) *Table[T] {
+	bound := TxTable[T](tx)
+	bound.validate = m.validate
+	return bound
+}
+
+// WithValidation 返回写入前校验记录的模型副本
+// 校验在Before钩子之后、写入之前执行,与钩子和写入位于同一事务,未通过时返回ValidationErrors并回滚
+func (m *Table[T]) WithValidation() *Table[T] {
+	validated := *m
+	validated.validate = true
+	return &validated
+}
+
+// Repository 返回模型使用的数据库句柄
+func (m *Table[T]) Repository() *Repository {
+	return m.repo
+}
+
+// WithTx 在事务中执行fn,模型已绑定事务时使用保存点嵌套执行
+func (m *Table[T]) WithTx(ctx context.Context, fn func(tx *Tx) error) error {
+	return m.repo.WithTx(ctx, fn)
 }
 
 // FindAll 查询所有记录
 func (m *Table[T]) FindAll(ctx context.Context) ([]T, error) {
-	rows, err := FindAll_mysql(ctx, m.db, m.table)
+	rows, err := m.repo.FindAll(ctx, m.table)
 	if err != nil {
 		return nil, err
 	}
@@ -52,22 +60,30 @@ func (m *Table[T]) FindAll(ctx context.Context) ([]T, error) {
 	for i, row := range rows {
 		result[i] = row.(T)
 	}
+	if err := afterFind(ctx, result); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
-// FindOneById 根据ID查询单条记录
-func (m *Table[T]) FindOneById(ctx context.Context, id int64) (T, error) {
-	row, err := FindOneById_mysql(ctx, m.db, m.table, id)
+// FindOneById 根据ID查询单条记录,fieldFilter为nil时查询全部字段,记录不存在时返回ErrNotFound
+func (m *Table[T]) FindOneById(ctx context.Context, id int64, fieldFilter *FieldFilter) (T, error) {
+	row, err := m.repo.FindOneById(ctx, m.table, id, fieldFilter)
 	if err != nil {
 		var zero T
 		return zero, err
 	}
-	return row.(T), nil
+	item := []T{row.(T)}
+	if err := afterFind(ctx, item); err != nil {
+		var zero T
+		return zero, err
+	}
+	return item[0], nil
 }
 
-// FindSomeByIds 根据ID列表查询多条记录
-func (m *Table[T]) FindSomeByIds(ctx context.Context, ids []int64) ([]T, error) {
-	rows, err := FindSomeByIds_mysql(ctx, m.db, m.table, ids)
+// FindSomeByIds 根据ID列表查询多条记录,fieldFilter为nil时查询全部字段
+func (m *Table[T]) FindSomeByIds(ctx context.Context, ids []int64, fieldFilter *FieldFilter) ([]T, error) {
+	rows, err := m.repo.FindSomeByIds(ctx, m.table, ids, fieldFilter)
 	if err != nil {
 		return nil, err
 	}
@@ -75,15 +91,18 @@ func (m *Table[T]) FindSomeByIds(ctx context.Context, ids []int64) ([]T, error)
 	for i, row := range rows {
 		result[i] = row.(T)
 	}
+	if err := afterFind(ctx, result); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
-// FindSomeByFilter 根据过滤条件查询记录
-func (m *Table[T]) FindSomeByFilter(ctx context.Context, filter *QueryFilter) ([]T, error) {
-	if filter == nil {
+// FindSomeByFilter 根据过滤条件查询记录,fieldFilter为nil时查询全部字段
+func (m *Table[T]) FindSomeByFilter(ctx context.Context, filter *QueryFilter, fieldFilter *FieldFilter) ([]T, error) {
+	if filter == nil && fieldFilter == nil {
 		return m.FindAll(ctx)
 	}
-	rows, err := FindSomeByFilter_mysql(ctx, m.db, m.table, filter)
+	rows, err := m.repo.FindSomeByFilter(ctx, m.table, filter, fieldFilter)
 	if err != nil {
 		return nil, err
 	}
@@ -91,50 +110,369 @@ func (m *Table[T]) FindSomeByFilter(ctx context.Context, filter *QueryFilter) ([
 	for i, row := range rows {
 		result[i] = row.(T)
 	}
+	if err := afterFind(ctx, result); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
-// FindOneByFilter 根据过滤条件查询单条记录
+// FindOneByFilter 根据过滤条件查询单条记录,没有符合条件的记录时返回ErrNotFound
 func (m *Table[T]) FindOneByFilter(ctx context.Context, filter *QueryFilter) (T, error) {
-	row, err := FindOneByFilter_mysql(ctx, m.db, m.table, filter)
+	row, err := m.repo.FindOneByFilter(ctx, m.table, filter)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	item := []T{row.(T)}
+	if err := afterFind(ctx, item); err != nil {
+		var zero T
+		return zero, err
+	}
+	return item[0], nil
+}
+
+// CountByFilter 根据过滤条件统计记录数
+func (m *Table[T]) CountByFilter(ctx context.Context, filter *QueryFilter) (int64, error) {
+	return m.repo.CountByFilter(ctx, m.table, filter)
+}
+
+// FindPageByFilter 根据过滤条件分页查询记录,同时返回总数和下一页游标
+// 游标分页通过多查询一条记录判断是否存在下一页,不执行COUNT,结果中的总数为nil
+func (m *Table[T]) FindPageByFilter(ctx context.Context, filter *QueryFilter, fieldFilter *FieldFilter) (*PageResult[T], error) {
+	if filter == nil || filter.Limit <= 0 {
+		return nil, fmt.Errorf("page size is required")
+	}
+	var total *int64
+	query := *filter
+	if filter.Cursor != nil {
+		query.Limit = filter.Limit + 1
+	} else {
+		count, err := m.CountByFilter(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		total = &count
+	}
+	// 存在下一页时两种分页方式都会返回next_cursor,生成游标需要读取排序键和id
+	columns := []string{"id"}
+	for _, key := range filter.SortKeys() {
+		columns = append(columns, key.Field)
+	}
+	fieldFilter = fieldFilter.WithColumns(columns...)
+	items, err := m.FindSomeByFilter(ctx, &query, fieldFilter)
+	if err != nil {
+		return nil, err
+	}
+	var hasNext bool
+	if filter.Cursor != nil {
+		hasNext = len(items) > filter.Limit
+		if hasNext {
+			items = items[:filter.Limit]
+		}
+	} else {
+		hasNext = int64(filter.Offset+len(items)) < *total
+	}
+	result := NewPageResult(items, total, filter, hasNext)
+	if hasNext && len(items) > 0 {
+		if result.NextCursor, err = NextCursor(items[len(items)-1], filter); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// LoadRelations 为items批量加载include中的关联,返回与items一一对应的关联数据,键为关联名称
+func (m *Table[T]) LoadRelations(ctx context.Context, items []T, include []string) ([]map[string]interface{}, error) {
+	rows := make([]ITable, len(items))
+	for i, item := range items {
+		rows[i] = item
+	}
+	return m.repo.LoadRelations(ctx, m.table, rows, include)
+}
+
+// Validate 按校验规则校验待创建的记录,未通过时返回ValidationErrors
+func (m *Table[T]) Validate(ctx context.Context, item T) error {
+	return validateStruct(ctx, m.repo, item, false)
+}
+
+// ValidateMany 校验多条待创建的记录,错误的字段名以记录下标为前缀,例如[2].name
+func (m *Table[T]) ValidateMany(ctx context.Context, items []T) error {
+	return validateItems(ctx, m.repo, items)
+}
+
+// validateItems 校验多条待创建的记录,错误的字段名以记录下标为前缀
+func validateItems[T ITable](ctx context.Context, r *Repository, items []T) error {
+	var fieldErrs ValidationErrors
+	for i, item := range items {
+		err := validateStruct(ctx, r, item, false)
+		if err == nil {
+			continue
+		}
+		itemErrs, ok := err.(ValidationErrors)
+		if !ok {
+			return err
+		}
+		for _, fieldErr := range itemErrs {
+			fieldErr.Field = fmt.Sprintf("[%d].%s", i, fieldErr.Field)
+			fieldErrs = append(fieldErrs, fieldErr)
+		}
+	}
+	if len(fieldErrs) > 0 {
+		return fieldErrs
+	}
+	return nil
+}
+
+// ValidateUpdate 校验更新内容,只校验客户端提供的字段
+func (m *Table[T]) ValidateUpdate(ctx context.Context, table ITableUpdate) error {
+	return validateStruct(ctx, m.repo, table, true)
+}
+
+// withHooks 执行写操作,hooked为true时在事务中执行,钩子返回错误时整个操作回滚
+// fn接收绑定到该事务的模型,未启用钩子时接收模型本身
+func (m *Table[T]) withHooks(ctx context.Context, hooked bool, fn func(bound *Table[T], tx *Tx) error) error {
+	if !hooked {
+		return fn(m, m.repo.tx)
+	}
+	return m.WithTx(ctx, func(tx *Tx) error {
+		return fn(m.InTx(tx), tx)
+	})
+}
+
+// CreateOne 创建新记录,返回包含生成ID的完整记录
+func (m *Table[T]) CreateOne(ctx context.Context, item T) (T, error) {
+	var created T
+	err := m.withHooks(ctx, hasCreateHooks[T]() || m.validate, func(bound *Table[T], tx *Tx) error {
+		if err := beforeCreate(ctx, tx, &item); err != nil {
+			return err
+		}
+		if m.validate {
+			if err := validateStruct(ctx, bound.repo, item, false); err != nil {
+				return err
+			}
+		}
+		id, err := bound.repo.CreateOne(ctx, item)
+		if err != nil {
+			return err
+		}
+		if created, err = bound.FindOneById(ctx, id, nil); err != nil {
+			return err
+		}
+		return afterCreate(ctx, tx, &created)
+	})
 	if err != nil {
 		var zero T
 		return zero, err
 	}
-	return row.(T), nil
+	return created, nil
+}
+
+// CreateMany 批量创建记录,多个批次在同一事务中执行,返回生成的ID
+// MySQL下返回的自增ID依赖连续分配,对innodb_autoinc_lock_mode的要求见Repository.CreateMany
+func (m *Table[T]) CreateMany(ctx context.Context, items []T) ([]int64, error) {
+	var ids []int64
+	err := m.WithTx(ctx, func(tx *Tx) error {
+		tables := make([]ITable, len(items))
+		for i := range items {
+			if err := beforeCreate(ctx, tx, &items[i]); err != nil {
+				return err
+			}
+			tables[i] = items[i]
+		}
+		if m.validate {
+			if err := validateItems(ctx, tx.repo, items); err != nil {
+				return err
+			}
+		}
+		var err error
+		if ids, err = tx.repo.CreateMany(ctx, tables); err != nil {
+			return err
+		}
+		for i := range items {
+			setId(&items[i], ids[i])
+			if err := afterCreate(ctx, tx, &items[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// UpsertOne 插入记录,冲突时更新updateColumns指定的列,返回影响的行数
+func (m *Table[T]) UpsertOne(ctx context.Context, item T, updateColumns []string) (int64, error) {
+	return m.UpsertMany(ctx, []T{item}, updateColumns)
+}
+
+// UpsertMany 批量插入记录,冲突时更新updateColumns指定的列,多个批次在同一事务中执行
+// 只调用BeforeCreate钩子,冲突更新的记录无法确定ID,不调用AfterCreate
+func (m *Table[T]) UpsertMany(ctx context.Context, items []T, updateColumns []string) (int64, error) {
+	var affected int64
+	err := m.WithTx(ctx, func(tx *Tx) error {
+		tables := make([]ITable, len(items))
+		for i := range items {
+			if err := beforeCreate(ctx, tx, &items[i]); err != nil {
+				return err
+			}
+			tables[i] = items[i]
+		}
+		if m.validate {
+			if err := validateItems(ctx, tx.repo, items); err != nil {
+				return err
+			}
+		}
+		var err error
+		affected, err = tx.repo.UpsertMany(ctx, tables, updateColumns)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// update 在更新钩子之间执行exec,没有记录被更新时不调用AfterUpdate
+func (m *Table[T]) update(ctx context.Context, table ITableUpdate, exec func(bound *Table[T], table ITableUpdate) (int64, error)) (int64, error) {
+	payload := updatePayload(table)
+	var affected int64
+	err := m.withHooks(ctx, hasUpdateHooks(payload) || m.validate, func(bound *Table[T], tx *Tx) error {
+		if err := beforeUpdate(ctx, tx, payload); err != nil {
+			return err
+		}
+		if m.validate {
+			if err := validateStruct(ctx, bound.repo, updateValue(payload), true); err != nil {
+				return err
+			}
+		}
+		var err error
+		if affected, err = exec(bound, updateValue(payload)); err != nil || affected == 0 {
+			return err
+		}
+		return afterUpdate(ctx, tx, payload)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// UpdateOne 更新记录,返回影响的行数
+func (m *Table[T]) UpdateOne(ctx context.Context, table ITableUpdate) (int64, error) {
+	return m.update(ctx, table, func(bound *Table[T], table ITableUpdate) (int64, error) {
+		return bound.repo.UpdateOne(ctx, table)
+	})
 }
 
-// CreateOne 创建新记录
-func (m *Table[T]) CreateOne(ctx context.Context, table ITable) error {
-	return CreateOne_mysql(ctx, m.db, table)
+// UpdateSomeByIds 根据ID列表批量更新记录,返回影响的行数
+func (m *Table[T]) UpdateSomeByIds(ctx context.Context, table ITableUpdate, ids []int64) (int64, error) {
+	return m.update(ctx, table, func(bound *Table[T], table ITableUpdate) (int64, error) {
+		return bound.repo.UpdateSomeByIds(ctx, table, ids)
+	})
 }
 
-// UpdateOne 更新记录
-func (m *Table[T]) UpdateOne(ctx context.Context, table ITableUpdate) error {
-	return UpdateOne_mysql(ctx, m.db, table)
+// UpdateSomeByFilter 使用过滤条件更新记录,返回影响的行数
+func (m *Table[T]) UpdateSomeByFilter(ctx context.Context, table ITableUpdate, filter *QueryFilter) (int64, error) {
+	return m.update(ctx, table, func(bound *Table[T], table ITableUpdate) (int64, error) {
+		return bound.repo.UpdateSomeByFilter(ctx, m.table, table, filter)
+	})
 }
 
-// UpdateSomeByIds 更新单条记录
-func (m *Table[T]) UpdateSomeByIds(ctx context.Context, table ITableUpdate, ids []int64) error {
-	return UpdateSomeByIds_mysql(ctx, m.db, table, ids)
+// delete 在删除钩子之间执行exec,没有记录被删除时不调用AfterDelete
+func (m *Table[T]) delete(ctx context.Context, ids []int64, exec func(bound *Table[T]) (int64, error)) (int64, error) {
+	hooks := hasDeleteHooks[T]()
+	var affected int64
+	err := m.withHooks(ctx, hooks, func(bound *Table[T], tx *Tx) error {
+		// 实现了删除钩子时先在事务中查出待删除的记录,钩子在完整的记录上调用
+		var items []T
+		if hooks {
+			rows, err := bound.repo.FindSomeByIds(ctx, m.table, ids, nil)
+			if err != nil {
+				return err
+			}
+			items = make([]T, len(rows))
+			for i, row := range rows {
+				items[i] = row.(T)
+			}
+			if err := beforeDelete(ctx, tx, items); err != nil {
+				return err
+			}
+		}
+		var err error
+		if affected, err = exec(bound); err != nil || affected == 0 {
+			return err
+		}
+		return afterDelete(ctx, tx, items)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return affected, nil
 }
 
-// UpdateSomeByFilter 使用过滤条件更新记录
-func (m *Table[T]) UpdateSomeByFilter(ctx context.Context, table ITableUpdate, filter *QueryFilter) error {
-	return UpdateSomeByFilter_mysql(ctx, m.db, m.table, table, filter)
+// DeleteOne 删除单条记录,返回影响的行数
+func (m *Table[T]) DeleteOne(ctx context.Context, id int64) (int64, error) {
+	return m.delete(ctx, []int64{id}, func(bound *Table[T]) (int64, error) {
+		return bound.repo.DeleteOneById(ctx, m.table, id)
+	})
 }
 
-// DeleteOne 删除单条记录
-func (m *Table[T]) DeleteOne(ctx context.Context, id int64) error {
-	return DeleteOneById_mysql(ctx, m.db, m.table, id)
+// DeleteSomeByIds 批量删除记录,返回影响的行数
+func (m *Table[T]) DeleteSomeByIds(ctx context.Context, ids []int64) (int64, error) {
+	return m.delete(ctx, ids, func(bound *Table[T]) (int64, error) {
+		return bound.repo.DeleteSomeByIds(ctx, m.table, ids)
+	})
 }
 
-// DeleteSomeByIds 批量删除记录
-func (m *Table[T]) DeleteSomeByIds(ctx context.Context, ids []int64) error {
-	return DeleteSomeByIds_mysql(ctx, m.db, m.table, ids)
+// DeleteSomeByFilter 根据过滤条件删除记录,返回影响的行数
+// 实现了删除钩子时先在事务中查出符合条件的ID,再按ID删除
+func (m *Table[T]) DeleteSomeByFilter(ctx context.Context, filter *QueryFilter) (int64, error) {
+	if !hasDeleteHooks[T]() {
+		return m.repo.DeleteSomeByFilter(ctx, m.table, filter)
+	}
+	var affected int64
+	err := m.WithTx(ctx, func(tx *Tx) error {
+		rows, err := tx.repo.FindSomeByFilter(ctx, m.table, filter, &FieldFilter{RequiredFields: []string{"id"}})
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		ids := make([]int64, len(rows))
+		for i, row := range rows {
+			ids[i] = row.GetId()
+		}
+		affected, err = m.InTx(tx).DeleteSomeByIds(ctx, ids)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// Restore 恢复已软删除的记录,返回影响的行数
+func (m *Table[T]) Restore(ctx context.Context, ids []int64) (int64, error) {
+	return m.repo.RestoreSomeByIds(ctx, m.table, ids)
+}
+
+// FindDeleted 根据过滤条件查询已软删除的记录
+func (m *Table[T]) FindDeleted(ctx context.Context, filter *QueryFilter, fieldFilter *FieldFilter) ([]T, error) {
+	if !IsSoftDelete(m.table) {
+		return nil, ErrSoftDeleteUnsupported
+	}
+	scoped := QueryFilter{}
+	if filter != nil {
+		scoped = *filter
+	}
+	scoped.Deleted = OnlyDeleted
+	return m.FindSomeByFilter(ctx, &scoped, fieldFilter)
 }
 
-// DeleteSomeByFilter 根据过滤条件删除记录
-func (m *Table[T]) DeleteSomeByFilter(ctx context.Context, filter *QueryFilter) error {
-	return DeleteSomeByFilter_mysql(ctx, m.db, m.table, filter)
+// PurgeDeleted 物理删除符合过滤条件的已软删除记录,返回影响的行数
+func (m *Table[T]) PurgeDeleted(ctx context.Context, filter *QueryFilter) (int64, error) {
+	return m.repo.PurgeDeleted(ctx, m.table, filter)
 }
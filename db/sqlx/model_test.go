@@ -0,0 +1,320 @@
+package sqlx
+
+import (
+	"context"
+	"crud/db"
+	sqlc "crud/db/sqlc"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 测试使用的SQLite表结构,与 db/migrations/scheme.sql 对应
+const testSchema = `
+CREATE TABLE authors (
+  id   INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT    NOT NULL,
+  bio  TEXT
+);
+CREATE TABLE books (
+  id        INTEGER PRIMARY KEY AUTOINCREMENT,
+  title     TEXT    NOT NULL,
+  author_id INTEGER NOT NULL REFERENCES authors(id)
+);`
+
+// newTestRepository 创建建好表结构的内存SQLite数据库句柄
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	conn, err := db.NewSQLiteConnector(":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for _, statement := range strings.Split(testSchema, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := conn.Exec(statement); err != nil {
+			t.Fatalf("apply schema: %v", err)
+		}
+	}
+	return NewRepository(conn, SQLite)
+}
+
+// seedAuthors 创建测试用的作者,返回按创建顺序排列的ID
+func seedAuthors(t *testing.T, authors *Table[sqlc.Author]) []int64 {
+	t.Helper()
+	ids, err := authors.CreateMany(context.Background(), []sqlc.Author{
+		{Name: "tom", Bio: sql.NullString{String: "cat", Valid: true}},
+		{Name: "jerry"},
+		{Name: "spike", Bio: sql.NullString{String: "dog", Valid: true}},
+		{Name: "tyke", Bio: sql.NullString{String: "puppy", Valid: true}},
+	})
+	if err != nil {
+		t.Fatalf("seed authors: %v", err)
+	}
+	return ids
+}
+
+// authorNames 返回记录的作者名,用于比较查询结果
+func authorNames(items []sqlc.Author) []string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	return names
+}
+
+func TestTableCreateAndFind(t *testing.T) {
+	ctx := context.Background()
+	authors := Model[sqlc.Author](newTestRepository(t))
+
+	created, err := authors.CreateOne(ctx, sqlc.Author{Name: "tom", Bio: sql.NullString{String: "cat", Valid: true}})
+	if err != nil {
+		t.Fatalf("CreateOne: %v", err)
+	}
+	if created.ID == 0 || created.Name != "tom" || created.Bio.String != "cat" {
+		t.Fatalf("CreateOne returned %+v", created)
+	}
+
+	ids, err := authors.CreateMany(ctx, []sqlc.Author{{Name: "jerry"}, {Name: "spike"}, {Name: "tyke"}})
+	if err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	if want := []int64{created.ID + 1, created.ID + 2, created.ID + 3}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("CreateMany ids = %v, want %v", ids, want)
+	}
+
+	found, err := authors.FindOneById(ctx, ids[1], nil)
+	if err != nil {
+		t.Fatalf("FindOneById: %v", err)
+	}
+	if found.ID != ids[1] || found.Name != "spike" || found.Bio.Valid {
+		t.Fatalf("FindOneById returned %+v", found)
+	}
+	if _, err := authors.FindOneById(ctx, 100, nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FindOneById of a missing id: err = %v, want ErrNotFound", err)
+	}
+
+	items, err := authors.FindSomeByIds(ctx, ids, &FieldFilter{RequiredFields: []string{"id", "name"}})
+	if err != nil {
+		t.Fatalf("FindSomeByIds: %v", err)
+	}
+	if got := authorNames(items); !reflect.DeepEqual(got, []string{"jerry", "spike", "tyke"}) {
+		t.Fatalf("FindSomeByIds names = %v", got)
+	}
+}
+
+func TestTableFindSomeByFilter(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	authors := Model[sqlc.Author](repo)
+	ids := seedAuthors(t, authors)
+
+	tests := []struct {
+		name   string
+		filter *QueryFilter
+		want   []string
+	}{
+		{"eq", &QueryFilter{Conditions: []*QueryCondition{{Field: "name", Value: "tom", Operator: "="}}}, []string{"tom"}},
+		{"ne", &QueryFilter{Conditions: []*QueryCondition{{Field: "name", Value: "tom", Operator: "!="}}}, []string{"jerry", "spike", "tyke"}},
+		{"gt", &QueryFilter{Conditions: []*QueryCondition{{Field: "id", Value: ids[2], Operator: ">"}}}, []string{"tyke"}},
+		{"gte", &QueryFilter{Conditions: []*QueryCondition{{Field: "id", Value: ids[2], Operator: ">="}}}, []string{"spike", "tyke"}},
+		{"lt", &QueryFilter{Conditions: []*QueryCondition{{Field: "id", Value: ids[1], Operator: "<"}}}, []string{"tom"}},
+		{"lte", &QueryFilter{Conditions: []*QueryCondition{{Field: "id", Value: ids[1], Operator: "<="}}}, []string{"tom", "jerry"}},
+		{"like is case insensitive", &QueryFilter{Conditions: []*QueryCondition{{Field: "name", Value: "TOM", Operator: "like"}}}, []string{"tom"}},
+		{"in", &QueryFilter{Conditions: []*QueryCondition{{Field: "name", Value: []interface{}{"tom", "spike"}, Operator: "IN"}}}, []string{"tom", "spike"}},
+		{"not in", &QueryFilter{Conditions: []*QueryCondition{{Field: "name", Value: []string{"tom", "spike"}, Operator: "NOT IN"}}}, []string{"jerry", "tyke"}},
+		{"between", &QueryFilter{Conditions: []*QueryCondition{{Field: "id", Value: []int64{ids[1], ids[2]}, Operator: "BETWEEN"}}}, []string{"jerry", "spike"}},
+		{"is null", &QueryFilter{Conditions: []*QueryCondition{{Field: "bio", Operator: "IS NULL"}}}, []string{"jerry"}},
+		{"is not null", &QueryFilter{Conditions: []*QueryCondition{{Field: "bio", Operator: "IS NOT NULL"}}}, []string{"tom", "spike", "tyke"}},
+		{"conditions are joined with and", &QueryFilter{Conditions: []*QueryCondition{
+			{Field: "bio", Operator: "IS NOT NULL"},
+			{Field: "id", Value: ids[0], Operator: ">"},
+		}}, []string{"spike", "tyke"}},
+		{"or group", &QueryFilter{Groups: []*QueryGroup{{Logic: "OR", Conditions: []*QueryCondition{
+			{Field: "name", Value: "tom", Operator: "="},
+			{Field: "bio", Operator: "IS NULL"},
+		}}}}, []string{"tom", "jerry"}},
+		{"not group", &QueryFilter{Groups: []*QueryGroup{{Not: true, Conditions: []*QueryCondition{
+			{Field: "name", Value: "tom", Operator: "="},
+		}}}}, []string{"jerry", "spike", "tyke"}},
+		{"nested groups", &QueryFilter{Groups: []*QueryGroup{{Logic: "AND",
+			Conditions: []*QueryCondition{{Field: "bio", Operator: "IS NOT NULL"}},
+			Groups: []*QueryGroup{{Logic: "OR", Conditions: []*QueryCondition{
+				{Field: "name", Value: "tom", Operator: "="},
+				{Field: "name", Value: "tyke", Operator: "="},
+			}}},
+		}}}, []string{"tom", "tyke"}},
+		{"sorted with limit and offset", &QueryFilter{Sorts: []SortKey{{Field: "name", Order: "DESC"}}, Limit: 2, Offset: 1}, []string{"tom", "spike"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := authors.FindSomeByFilter(ctx, tt.filter, nil)
+			if err != nil {
+				t.Fatalf("FindSomeByFilter: %v", err)
+			}
+			if got := authorNames(items); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("names = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid field", func(t *testing.T) {
+		filter := &QueryFilter{Conditions: []*QueryCondition{{Field: "password", Value: "x", Operator: "="}}}
+		if _, err := authors.FindSomeByFilter(ctx, filter, nil); !errors.Is(err, ErrInvalidField) {
+			t.Fatalf("err = %v, want ErrInvalidField", err)
+		}
+	})
+}
+
+func TestTableFindByRelationFilter(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	authors := Model[sqlc.Author](repo)
+	books := Model[sqlc.Book](repo)
+	ids := seedAuthors(t, authors)
+	if _, err := books.CreateMany(ctx, []sqlc.Book{
+		{Title: "cat and mouse", AuthorID: ids[0]},
+		{Title: "the chase", AuthorID: ids[0]},
+		{Title: "guard dog", AuthorID: ids[2]},
+	}); err != nil {
+		t.Fatalf("seed books: %v", err)
+	}
+
+	found, err := books.FindSomeByFilter(ctx, &QueryFilter{Conditions: []*QueryCondition{
+		{Field: "author.name", Value: "spike", Operator: "="},
+	}}, nil)
+	if err != nil {
+		t.Fatalf("filter by belongs_to: %v", err)
+	}
+	if len(found) != 1 || found[0].Title != "guard dog" {
+		t.Fatalf("filter by belongs_to returned %+v", found)
+	}
+
+	writers, err := authors.FindSomeByFilter(ctx, &QueryFilter{Conditions: []*QueryCondition{
+		{Field: "books.title", Value: "the chase", Operator: "="},
+	}}, nil)
+	if err != nil {
+		t.Fatalf("filter by has_many: %v", err)
+	}
+	if got := authorNames(writers); !reflect.DeepEqual(got, []string{"tom"}) {
+		t.Fatalf("filter by has_many names = %v", got)
+	}
+}
+
+func TestTableFindPageByFilter(t *testing.T) {
+	ctx := context.Background()
+	authors := Model[sqlc.Author](newTestRepository(t))
+	seedAuthors(t, authors)
+
+	t.Run("offset", func(t *testing.T) {
+		// 返回字段不含排序键和id时,next_cursor仍按最后一条记录生成
+		fieldFilter := &FieldFilter{RequiredFields: []string{"bio"}}
+		page, err := authors.FindPageByFilter(ctx, &QueryFilter{SortField: "name", SortOrder: "ASC", Limit: 3}, fieldFilter)
+		if err != nil {
+			t.Fatalf("FindPageByFilter: %v", err)
+		}
+		if page.Total == nil || *page.Total != 4 || !page.HasNext || page.Page != 1 || page.PageSize != 3 {
+			t.Fatalf("page = %+v", page)
+		}
+		if got := authorNames(page.Items); !reflect.DeepEqual(got, []string{"jerry", "spike", "tom"}) {
+			t.Fatalf("names = %v", got)
+		}
+		cursor, err := DecodeCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("DecodeCursor: %v", err)
+		}
+		if cursor.Id != page.Items[2].ID || !reflect.DeepEqual(cursor.Values, []interface{}{"tom"}) {
+			t.Fatalf("next cursor = %+v", cursor)
+		}
+
+		last, err := authors.FindPageByFilter(ctx, &QueryFilter{SortField: "name", SortOrder: "ASC", Limit: 3, Offset: 3}, nil)
+		if err != nil {
+			t.Fatalf("FindPageByFilter: %v", err)
+		}
+		if last.HasNext || last.NextCursor != "" || last.Page != 2 {
+			t.Fatalf("last page = %+v", last)
+		}
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		fieldFilter := &FieldFilter{RequiredFields: []string{"bio"}}
+		filter := &QueryFilter{Sorts: []SortKey{{Field: "name", Order: "DESC"}}, Limit: 3, Cursor: &Cursor{Values: []interface{}{"~"}}}
+		var names []string
+		for pages := 0; ; pages++ {
+			if pages > 4 {
+				t.Fatal("cursor paging does not terminate")
+			}
+			page, err := authors.FindPageByFilter(ctx, filter, fieldFilter)
+			if err != nil {
+				t.Fatalf("FindPageByFilter: %v", err)
+			}
+			// 游标分页不统计总数
+			if page.Total != nil {
+				t.Fatalf("cursor page total = %d, want nil", *page.Total)
+			}
+			names = append(names, authorNames(page.Items)...)
+			if !page.HasNext {
+				break
+			}
+			if filter.Cursor, err = DecodeCursor(page.NextCursor); err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+		}
+		if want := []string{"tyke", "tom", "spike", "jerry"}; !reflect.DeepEqual(names, want) {
+			t.Fatalf("names = %v, want %v", names, want)
+		}
+	})
+}
+
+func TestTableUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	authors := Model[sqlc.Author](newTestRepository(t))
+	ids := seedAuthors(t, authors)
+
+	name := "thomas"
+	affected, err := authors.UpdateOne(ctx, sqlc.AuthorUpdate{Id: ids[0], Name: &name})
+	if err != nil || affected != 1 {
+		t.Fatalf("UpdateOne: affected = %d, err = %v", affected, err)
+	}
+	updated, err := authors.FindOneById(ctx, ids[0], nil)
+	if err != nil {
+		t.Fatalf("FindOneById: %v", err)
+	}
+	// 未提供的字段保持不变
+	if updated.Name != "thomas" || updated.Bio.String != "cat" {
+		t.Fatalf("updated = %+v", updated)
+	}
+
+	bio := "pet"
+	affected, err = authors.UpdateSomeByIds(ctx, sqlc.AuthorUpdate{Bio: &bio}, ids[1:3])
+	if err != nil || affected != 2 {
+		t.Fatalf("UpdateSomeByIds: affected = %d, err = %v", affected, err)
+	}
+	pets, err := authors.CountByFilter(ctx, &QueryFilter{Conditions: []*QueryCondition{{Field: "bio", Value: "pet", Operator: "="}}})
+	if err != nil || pets != 2 {
+		t.Fatalf("count pets = %d, err = %v", pets, err)
+	}
+
+	affected, err = authors.DeleteSomeByFilter(ctx, &QueryFilter{Conditions: []*QueryCondition{
+		{Field: "name", Value: []interface{}{"jerry", "tyke"}, Operator: "IN"},
+	}})
+	if err != nil || affected != 2 {
+		t.Fatalf("DeleteSomeByFilter: affected = %d, err = %v", affected, err)
+	}
+	// SQLite的DELETE不支持ORDER BY和LIMIT,改为按子查询选出的id删除
+	affected, err = authors.DeleteSomeByFilter(ctx, &QueryFilter{SortField: "name", SortOrder: "ASC", Limit: 1})
+	if err != nil || affected != 1 {
+		t.Fatalf("DeleteSomeByFilter with limit: affected = %d, err = %v", affected, err)
+	}
+	rest, err := authors.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if got := authorNames(rest); !reflect.DeepEqual(got, []string{"thomas"}) {
+		t.Fatalf("remaining = %v", got)
+	}
+}
@@ -0,0 +1,39 @@
+package sqlx
+
+// PageResult 分页查询结果
+type PageResult[T any] struct {
+	Items      []T    `json:"items"`                 // 当前页记录
+	Total      *int64 `json:"total,omitempty"`       // 符合条件的总记录数,游标分页时不统计
+	Page       int    `json:"page"`                  // 当前页码,从1开始,游标分页时固定为1
+	PageSize   int    `json:"page_size"`             // 每页记录数
+	HasNext    bool   `json:"has_next"`              // 是否存在下一页
+	NextCursor string `json:"next_cursor,omitempty"` // 下一页游标,存在下一页时返回
+}
+
+// NewPageResult 根据查询结果和过滤条件构建分页结果,total为nil表示未统计总数
+func NewPageResult[T any](items []T, total *int64, filter *QueryFilter, hasNext bool) *PageResult[T] {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	page, pageSize := filter.Page()
+	return &PageResult[T]{
+		Items:    items,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		HasNext:  hasNext,
+	}
+}
+
+// IsPaged 判断过滤条件是否包含分页参数
+func (f *QueryFilter) IsPaged() bool {
+	return f != nil && f.Limit > 0
+}
+
+// Page 根据Limit/Offset计算页码和每页记录数
+func (f *QueryFilter) Page() (page int, pageSize int) {
+	if !f.IsPaged() {
+		return 1, 0
+	}
+	return f.Offset/f.Limit + 1, f.Limit
+}
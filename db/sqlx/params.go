@@ -3,45 +3,123 @@ package sqlx
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
 )
 
 // QueryFilter 包含分页、排序和过滤条件
 type QueryFilter struct {
 	Conditions []*QueryCondition // 过滤条件列表
+	Groups     []*QueryGroup     // 条件分组列表,与Conditions之间以AND连接
 	Limit      int               // 限制返回的记录数
 	Offset     int               // 偏移量
 	SortField  string            // 排序字段名
 	SortOrder  string            // 排序方式(ASC/DESC)
+	Sorts      []SortKey         // 多字段排序,设置后优先于SortField/SortOrder
+	Cursor     *Cursor           // 游标分页位置,设置后忽略Offset
+	Deleted    DeletedScope      // 软删除记录的查询范围,仅对声明了软删除列的表生效
+}
+
+// SortKey 定义单个排序键
+type SortKey struct {
+	Field string // 排序字段名
+	Order string // 排序方式(ASC/DESC)
 }
 
 // QueryCondition 定义单个过滤条件
 type QueryCondition struct {
 	Field    string      // 字段名
 	Value    interface{} // 字段值
-	Operator string      // 操作符(=,>,>=,<,<=,LIKE等)
+	Operator string      // 操作符(=,!=,>,>=,<,<=,LIKE,IN,NOT IN,BETWEEN,IS NULL,IS NOT NULL)
+}
+
+// QueryGroup 定义一组过滤条件及其逻辑关系,可嵌套形成条件树
+type QueryGroup struct {
+	Logic      string            // 组内连接方式(AND/OR),为空时按AND处理
+	Not        bool              // 是否对整组取反
+	Conditions []*QueryCondition // 组内条件
+	Groups     []*QueryGroup     // 嵌套的子分组
 }
 
-var allowedOperators = map[string]bool{"=": true, ">": true, ">=": true, "<": true, "<=": true, "LIKE": true}
+var allowedOperators = map[string]bool{
+	"=": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true, "LIKE": true,
+	"IN": true, "NOT IN": true, "BETWEEN": true, "IS NULL": true, "IS NOT NULL": true,
+}
 
 // 定义支持的操作符映射
 var operatorMap = map[string]string{
-	"gt":   ">",
-	"gte":  ">=",
-	"lt":   "<",
-	"lte":  "<=",
-	"like": "LIKE",
+	"ne":      "!=",
+	"gt":      ">",
+	"gte":     ">=",
+	"lt":      "<",
+	"lte":     "<=",
+	"like":    "LIKE",
+	"in":      "IN",
+	"nin":     "NOT IN",
+	"between": "BETWEEN",
+	"null":    "IS NULL",
+}
+
+// FilterOperators 返回查询参数支持的操作符后缀及对应的SQL操作符,例如 like -> LIKE
+// 不带后缀的参数按 = 处理,null后缀的值为false时表示IS NOT NULL
+func FilterOperators() map[string]string {
+	operators := make(map[string]string, len(operatorMap))
+	for suffix, operator := range operatorMap {
+		operators[suffix] = operator
+	}
+	return operators
+}
+
+// IN/NOT IN 列表允许的最大元素数
+const maxInValues = 1000
+
+// 分组查询参数对应的逻辑连接方式
+var groupParams = map[string]string{
+	"and": "AND",
+	"or":  "OR",
+	"not": "AND",
 }
 
+// 保留的查询参数,不作为过滤条件解析
+var reservedParams = map[string]struct{}{
+	"page":            {},
+	"page_size":       {},
+	"sort":            {},
+	"sort_field":      {},
+	"sort_order":      {},
+	RequiredFieldsKey: {},
+	OmittedFieldsKey:  {},
+	CursorKey:         {},
+	IncludeKey:        {},
+	"and":             {},
+	"or":              {},
+	"not":             {},
+}
+
+// 条件分组允许的最大嵌套层数
+const maxGroupDepth = 5
+
+// 多字段排序允许的最大字段数
+const maxSortKeys = 5
+
 // ParseQueryConditionFromUrlParam 从URL查询参数解析过滤条件
 // 支持的格式:
+// field_ne=value -> field != value
 // field_gt=value -> field > value
 // field_gte=value -> field >= value
 // field_lt=value -> field < value
 // field_lte=value -> field <= value
 // field_like=value -> field LIKE value
+// field_in=a,b,c -> field IN (a, b, c)
+// field_nin=a,b,c -> field NOT IN (a, b, c)
+// field_between=a,b -> field BETWEEN a AND b
+// field_null=true -> field IS NULL
+// field_null=false -> field IS NOT NULL
 // field=value -> field = value
+// relation.field_like=value -> 按关联表的列过滤,relation为表声明的关联名称
 func ParseQueryConditionFromUrlParam(field string, value string) (*QueryCondition, error) {
 	// 检查参数
 	if field == "" || value == "" {
@@ -51,33 +129,60 @@ func ParseQueryConditionFromUrlParam(field string, value string) (*QueryConditio
 	if len(field) > 64 {
 		return nil, errors.New("field name too long")
 	}
-	parts := strings.Split(field, "_")
-	if len(parts) == 1 {
+	// 字段名本身可能包含下划线(如author_id),只有后缀是已知操作符时才拆分
+	idx := strings.LastIndex(field, "_")
+	if idx <= 0 {
 		return &QueryCondition{
 			Field:    field,
 			Value:    value,
 			Operator: "=",
 		}, nil
 	}
-	if len(parts) != 2 {
-		return nil, errors.New("invalid field format")
-	}
-
-	fieldName := parts[0]
-	op := parts[1]
-	operator, ok := operatorMap[op]
+	fieldName := field[:idx]
+	operator, ok := operatorMap[field[idx+1:]]
 	if !ok {
-		return nil, fmt.Errorf("unsupported operator: %s", op)
+		return &QueryCondition{
+			Field:    field,
+			Value:    value,
+			Operator: "=",
+		}, nil
 	}
 
-	// LIKE操作符特殊处理
-	if operator == "LIKE" {
+	switch operator {
+	case "LIKE":
+		// LIKE操作符特殊处理
 		if strings.ContainsAny(value, "%_\\'\"`;") || len(value) > 100 {
 			return nil, ErrInvalidLikeChar
 		}
 		if strings.Count(value, "%") > 2 {
 			return nil, errors.New("too many wildcards in LIKE pattern")
 		}
+	case "IN", "NOT IN", "BETWEEN":
+		// 列表值以逗号分隔,分组表达式中可用括号包裹,如 id_in:(1,2,3)
+		values, err := splitListValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkOperatorArity(operator, len(values)); err != nil {
+			return nil, err
+		}
+		return &QueryCondition{
+			Field:    fieldName,
+			Value:    values,
+			Operator: operator,
+		}, nil
+	case "IS NULL":
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid null value: %s", value)
+		}
+		if !isNull {
+			operator = "IS NOT NULL"
+		}
+		return &QueryCondition{
+			Field:    fieldName,
+			Operator: operator,
+		}, nil
 	}
 
 	return &QueryCondition{
@@ -87,10 +192,46 @@ func ParseQueryConditionFromUrlParam(field string, value string) (*QueryConditio
 	}, nil
 }
 
+// splitListValue 拆分逗号分隔的列表值
+func splitListValue(value string) ([]interface{}, error) {
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		value = value[1 : len(value)-1]
+	}
+	parts := strings.Split(value, ",")
+	values := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, errors.New("empty value in list")
+		}
+		values = append(values, part)
+	}
+	return values, nil
+}
+
+// checkOperatorArity 校验列表类操作符的值个数
+func checkOperatorArity(operator string, n int) error {
+	switch operator {
+	case "IN", "NOT IN":
+		if n == 0 || n > maxInValues {
+			return fmt.Errorf("%w: %s operator requires 1 to %d values", ErrInvalidValue, operator, maxInValues)
+		}
+	case "BETWEEN":
+		if n != 2 {
+			return fmt.Errorf("%w: BETWEEN operator requires exactly 2 values", ErrInvalidValue)
+		}
+	}
+	return nil
+}
+
 // ParseQueryConditionsFromUrlParams 从URL查询参数解析出查询条件
 func ParseQueryConditionsFromUrlParams(query map[string][]string) ([]*QueryCondition, error) {
 	var conditions []*QueryCondition
 	for field, values := range query {
+		// 跳过分页、排序等保留参数
+		if _, ok := reservedParams[field]; ok {
+			continue
+		}
 		// 跳过空值
 		if len(values) == 0 || values[0] == "" {
 			continue
@@ -106,12 +247,127 @@ func ParseQueryConditionsFromUrlParams(query map[string][]string) ([]*QueryCondi
 	return conditions, nil
 }
 
+// ParseQueryGroupsFromUrlParams 从URL查询参数解析出条件分组
+// 支持的格式:
+// or=(name_like:tom,bio_like:tom) -> (name LIKE tom OR bio LIKE tom)
+// and=(age_gt:18,or(name:tom,name:jerry)) -> (age > 18 AND (name = tom OR name = jerry))
+// not=(name:tom,bio:x) -> NOT (name = tom AND bio = x)
+// or=(id_in:(1,2,3),bio_null:true) -> (id IN (1, 2, 3) OR bio IS NULL)
+// 值中包含逗号或括号时可用双引号包裹,如 name:"a,b"
+func ParseQueryGroupsFromUrlParams(query map[string][]string) ([]*QueryGroup, error) {
+	var groups []*QueryGroup
+	for _, key := range []string{"and", "or", "not"} {
+		for _, value := range query[key] {
+			if value == "" {
+				continue
+			}
+			group, err := parseQueryGroup(key, value, 0)
+			if err != nil {
+				return nil, fmt.Errorf("解析分组失败 %s: %v", key, err)
+			}
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// parseQueryGroup 解析单个分组表达式
+func parseQueryGroup(key string, expr string, depth int) (*QueryGroup, error) {
+	if depth >= maxGroupDepth {
+		return nil, errors.New("group nesting too deep")
+	}
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") {
+		expr = expr[1 : len(expr)-1]
+	}
+	items, err := splitGroupItems(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("empty group")
+	}
+
+	group := &QueryGroup{
+		Logic: groupParams[key],
+		Not:   key == "not",
+	}
+	for _, item := range items {
+		// 嵌套分组: or(...), and(...), not(...)
+		if open := strings.Index(item, "("); open > 0 && strings.HasSuffix(item, ")") {
+			if _, ok := groupParams[item[:open]]; ok {
+				sub, err := parseQueryGroup(item[:open], item[open:], depth+1)
+				if err != nil {
+					return nil, err
+				}
+				group.Groups = append(group.Groups, sub)
+				continue
+			}
+		}
+		field, value, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid group item: %s", item)
+		}
+		condition, err := ParseQueryConditionFromUrlParam(strings.TrimSpace(field), unquoteGroupValue(value))
+		if err != nil {
+			return nil, err
+		}
+		group.Conditions = append(group.Conditions, condition)
+	}
+	return group, nil
+}
+
+// splitGroupItems 按顶层逗号拆分分组表达式,忽略括号和引号内的逗号
+func splitGroupItems(expr string) ([]string, error) {
+	var items []string
+	depth, start, quoted := 0, 0, false
+	for i, ch := range expr {
+		switch {
+		case ch == '"':
+			quoted = !quoted
+		case quoted:
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth < 0 {
+				return nil, errors.New("unbalanced parentheses")
+			}
+		case ch == ',' && depth == 0:
+			if item := strings.TrimSpace(expr[start:i]); item != "" {
+				items = append(items, item)
+			}
+			start = i + 1
+		}
+	}
+	if depth != 0 || quoted {
+		return nil, errors.New("unbalanced parentheses or quotes")
+	}
+	if item := strings.TrimSpace(expr[start:]); item != "" {
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// unquoteGroupValue 去除分组条件值两侧的双引号
+func unquoteGroupValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
 // ParseQueryFilterFromUrlParams 从echo.Context解析出Filter
 func ParseQueryFilterFromUrlParams(params map[string][]string) (*QueryFilter, error) {
 	conditions, err := ParseQueryConditionsFromUrlParams(params)
 	if err != nil {
 		return nil, err
 	}
+	groups, err := ParseQueryGroupsFromUrlParams(params)
+	if err != nil {
+		return nil, err
+	}
 
 	// 解析分页参数
 	var limit, offset int = 0, 0
@@ -130,6 +386,24 @@ func ParseQueryFilterFromUrlParams(params map[string][]string) (*QueryFilter, er
 		}
 	}
 
+	// 解析游标,游标分页不使用偏移量
+	var cursor *Cursor
+	if cursorValues, ok := params[CursorKey]; ok && len(cursorValues) > 0 && cursorValues[0] != "" {
+		cursor, err = DecodeCursor(cursorValues[0])
+		if err != nil {
+			return nil, err
+		}
+		offset = 0
+		if limit == 0 {
+			limit = 10
+			if sizeValues, ok := params["page_size"]; ok && len(sizeValues) > 0 {
+				if s, err := strconv.ParseInt(sizeValues[0], 10, 32); err == nil && s > 0 {
+					limit = int(s)
+				}
+			}
+		}
+	}
+
 	// 解析排序
 	var sortField, sortOrder string = "", ""
 	if fieldValues, ok := params["sort_field"]; ok && len(fieldValues) > 0 {
@@ -142,24 +416,101 @@ func ParseQueryFilterFromUrlParams(params map[string][]string) (*QueryFilter, er
 		}
 	}
 
-	if len(conditions) != 0 || limit != 0 || offset != 0 || sortField != "" || sortOrder != "" {
+	// 解析多字段排序 sort=-created_at,name
+	var sorts []SortKey
+	if sortValues, ok := params["sort"]; ok && len(sortValues) > 0 && sortValues[0] != "" {
+		sorts, err = ParseSortKeys(sortValues[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(conditions) != 0 || len(groups) != 0 || limit != 0 || offset != 0 || sortField != "" || sortOrder != "" || len(sorts) != 0 || cursor != nil {
 		return &QueryFilter{
 			Conditions: conditions,
+			Groups:     groups,
 			Limit:      limit,
 			Offset:     offset,
 			SortField:  sortField,
 			SortOrder:  sortOrder,
+			Sorts:      sorts,
+			Cursor:     cursor,
 		}, nil
 	}
 	return nil, nil
 }
 
+// ParseSortKeys 解析多字段排序参数
+// 以逗号分隔多个字段,字段前加"-"表示降序,加"+"或不加表示升序
+// 如 -created_at,name -> created_at DESC, name ASC
+func ParseSortKeys(value string) ([]SortKey, error) {
+	var keys []SortKey
+	seen := make(map[string]struct{})
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key := SortKey{Field: part, Order: "ASC"}
+		switch part[0] {
+		case '-':
+			key = SortKey{Field: part[1:], Order: "DESC"}
+		case '+':
+			key.Field = part[1:]
+		}
+		if key.Field == "" || len(key.Field) > 64 {
+			return nil, errors.New("invalid sort field")
+		}
+		if _, ok := seen[key.Field]; ok {
+			return nil, fmt.Errorf("duplicate sort field: %s", key.Field)
+		}
+		seen[key.Field] = struct{}{}
+		keys = append(keys, key)
+	}
+	if len(keys) > maxSortKeys {
+		return nil, fmt.Errorf("too many sort fields, max %d", maxSortKeys)
+	}
+	return keys, nil
+}
+
+// SortKeys 返回生效的排序键,Sorts优先,否则使用SortField/SortOrder
+func (f *QueryFilter) SortKeys() []SortKey {
+	if f == nil {
+		return nil
+	}
+	if len(f.Sorts) > 0 {
+		return f.Sorts
+	}
+	if f.SortField != "" {
+		return []SortKey{{Field: f.SortField, Order: f.SortOrder}}
+	}
+	return nil
+}
+
 // 在查询参数中标记需要的字段
 const RequiredFieldsKey = "atts_require"
 
 // 在查询参数中标记省略的字段
 const OmittedFieldsKey = "atts_omit"
 
+// 在查询参数中标记需要加载的关联,例如 include=books,author
+const IncludeKey = "include"
+
+// ParseIncludeFromQuery 从查询参数中解析需要加载的关联名称,去除重复的名称
+func ParseIncludeFromQuery(params map[string][]string) []string {
+	var include []string
+	seen := make(map[string]struct{})
+	for _, value := range params[IncludeKey] {
+		for _, name := range SplitFieldList(value) {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				include = append(include, name)
+			}
+		}
+	}
+	return include
+}
+
 // FieldFilter 结构体用于存储需要和省略的字段
 type FieldFilter struct {
 	RequiredFields []string // 需要的字段列表
@@ -168,69 +519,129 @@ type FieldFilter struct {
 
 // ParseFieldFilterFromQuery 从查询参数中解析出 FieldFilter
 func ParseFieldFilterFromQuery(params map[string][]string) (*FieldFilter, bool) {
-	for key, values := range params {
-		if key == RequiredFieldsKey {
-			fieldFilter := &FieldFilter{}
-			// 解析需要的字段
-			for _, value := range values {
-				fieldFilter.RequiredFields = append(fieldFilter.RequiredFields, strings.Split(value, ",")...)
-			}
-			return fieldFilter, true
-		}
-		if key == OmittedFieldsKey {
-			fieldFilter := &FieldFilter{}
-			// 解析省略的字段
-			for _, value := range values {
-				fieldFilter.OmittedFields = append(fieldFilter.OmittedFields, strings.Split(value, ",")...)
-			}
-			return fieldFilter, true
+	fieldFilter := &FieldFilter{}
+	// 解析需要的字段
+	for _, value := range params[RequiredFieldsKey] {
+		fieldFilter.RequiredFields = append(fieldFilter.RequiredFields, SplitFieldList(value)...)
+	}
+	// 解析省略的字段
+	for _, value := range params[OmittedFieldsKey] {
+		fieldFilter.OmittedFields = append(fieldFilter.OmittedFields, SplitFieldList(value)...)
+	}
+	if len(fieldFilter.RequiredFields) == 0 && len(fieldFilter.OmittedFields) == 0 {
+		return nil, false
+	}
+	return fieldFilter, true
+}
+
+// SplitFieldList 拆分逗号分隔的字段列表,去除空白并忽略空字段
+func SplitFieldList(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
 		}
 	}
-	return nil, false
+	return fields
 }
 
-func BuildSelectWithFieldFilter(table ITable, fieldFilter *FieldFilter) (string, []interface{}, error) {
+// Columns 返回过滤后的列名,顺序与表定义的列顺序一致
+func (f *FieldFilter) Columns(table ITable) ([]string, error) {
 	allowedFields := table.ColumnsMap()
-	var selectedFields []string
-
-	// 过滤需要的字段
-	if fieldFilter != nil {
-		if len(fieldFilter.RequiredFields) > 0 {
-			for _, field := range fieldFilter.RequiredFields {
-				if _, ok := allowedFields[field]; ok {
-					selectedFields = append(selectedFields, field)
-				}
-			}
-		} else {
-			for field := range allowedFields {
-				selectedFields = append(selectedFields, field)
+	required := make(map[string]struct{})
+	omitted := make(map[string]struct{})
+	if f != nil {
+		for _, field := range f.RequiredFields {
+			if _, ok := allowedFields[field]; ok {
+				required[field] = struct{}{}
 			}
 		}
+		for _, field := range f.OmittedFields {
+			omitted[field] = struct{}{}
+		}
+		// 指定的需要字段全部无效时不能退化为查询全部字段
+		if len(f.RequiredFields) > 0 && len(required) == 0 {
+			return nil, fmt.Errorf("no valid fields selected")
+		}
+	}
 
-		// 过滤省略的字段
-		if len(fieldFilter.OmittedFields) > 0 {
-			var filteredFields []string
-			omitMap := make(map[string]struct{})
-			for _, field := range fieldFilter.OmittedFields {
-				omitMap[field] = struct{}{}
-			}
-			for _, field := range selectedFields {
-				if _, ok := omitMap[field]; !ok {
-					filteredFields = append(filteredFields, field)
-				}
+	var selectedFields []string
+	for _, field := range table.Columns() {
+		if _, ok := allowedFields[field]; !ok {
+			continue
+		}
+		if len(required) > 0 {
+			if _, ok := required[field]; !ok {
+				continue
 			}
-			selectedFields = filteredFields
 		}
-	} else {
-		for field := range allowedFields {
-			selectedFields = append(selectedFields, field)
+		if _, ok := omitted[field]; ok {
+			continue
 		}
+		selectedFields = append(selectedFields, field)
 	}
-
 	if len(selectedFields) == 0 {
-		return "", nil, fmt.Errorf("no valid fields selected")
+		return nil, fmt.Errorf("no valid fields selected")
 	}
+	return selectedFields, nil
+}
 
-	query := fmt.Sprintf("SELECT %s FROM `%s`", strings.Join(selectedFields, ", "), table.TableName())
+// WithColumns 返回额外包含指定列的字段过滤器副本,用于内部需要的列(如游标排序键)
+func (f *FieldFilter) WithColumns(columns ...string) *FieldFilter {
+	if f == nil {
+		return nil
+	}
+	extra := make(map[string]struct{}, len(columns))
+	for _, column := range columns {
+		extra[column] = struct{}{}
+	}
+	result := &FieldFilter{}
+	if len(f.RequiredFields) > 0 {
+		result.RequiredFields = append(append(result.RequiredFields, f.RequiredFields...), columns...)
+	}
+	for _, field := range f.OmittedFields {
+		if _, ok := extra[field]; !ok {
+			result.OmittedFields = append(result.OmittedFields, field)
+		}
+	}
+	return result
+}
+
+// BuildSelectWithFieldFilter 根据字段过滤器构建SELECT语句,字段名使用引号包裹
+func BuildSelectWithFieldFilter(d Dialect, table ITable, fieldFilter *FieldFilter) (string, []interface{}, error) {
+	if fieldFilter == nil {
+		return buildBaseSelect(d, table.TableName()), nil, nil
+	}
+	selectedFields, err := fieldFilter.Columns(table)
+	if err != nil {
+		return "", nil, err
+	}
+	quotedFields := make([]string, len(selectedFields))
+	for i, field := range selectedFields {
+		quotedFields[i] = d.Quote(field)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quotedFields, ", "), d.Quote(table.TableName()))
 	return query, nil, nil
 }
+
+// PickFields 将记录转换为只包含指定列的map,键名使用json标签
+func PickFields(item ITable, columns []string) map[string]interface{} {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	fields := columnMapper.TypeMap(v.Type()).Names
+	result := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		fi, ok := fields[column]
+		if !ok {
+			continue
+		}
+		name := strings.Split(fi.Field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = fi.Field.Name
+		}
+		result[name] = reflectx.FieldByIndexesReadOnly(v, fi.Index).Interface()
+	}
+	return result
+}
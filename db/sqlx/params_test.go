@@ -0,0 +1,167 @@
+package sqlx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQueryConditionFromUrlParam(t *testing.T) {
+	tests := []struct {
+		field   string
+		value   string
+		want    *QueryCondition
+		wantErr bool
+	}{
+		{"name", "tom", &QueryCondition{Field: "name", Value: "tom", Operator: "="}, false},
+		// 后缀不是已知操作符时,整个参数名作为字段名
+		{"author_id", "1", &QueryCondition{Field: "author_id", Value: "1", Operator: "="}, false},
+		{"created_by_user", "1", &QueryCondition{Field: "created_by_user", Value: "1", Operator: "="}, false},
+		{"_in", "1", &QueryCondition{Field: "_in", Value: "1", Operator: "="}, false},
+		{"author_id_gt", "1", &QueryCondition{Field: "author_id", Value: "1", Operator: ">"}, false},
+		{"id_ne", "1", &QueryCondition{Field: "id", Value: "1", Operator: "!="}, false},
+		{"id_gte", "1", &QueryCondition{Field: "id", Value: "1", Operator: ">="}, false},
+		{"id_lt", "1", &QueryCondition{Field: "id", Value: "1", Operator: "<"}, false},
+		{"id_lte", "1", &QueryCondition{Field: "id", Value: "1", Operator: "<="}, false},
+		{"name_like", "tom", &QueryCondition{Field: "name", Value: "tom", Operator: "LIKE"}, false},
+		{"name_like", "to%m", nil, true},
+		{"id_in", "1, 2,3", &QueryCondition{Field: "id", Value: []interface{}{"1", "2", "3"}, Operator: "IN"}, false},
+		{"id_in", "(1,2)", &QueryCondition{Field: "id", Value: []interface{}{"1", "2"}, Operator: "IN"}, false},
+		{"id_in", "1,,2", nil, true},
+		{"id_nin", "1", &QueryCondition{Field: "id", Value: []interface{}{"1"}, Operator: "NOT IN"}, false},
+		{"id_between", "1,9", &QueryCondition{Field: "id", Value: []interface{}{"1", "9"}, Operator: "BETWEEN"}, false},
+		{"id_between", "1,2,3", nil, true},
+		{"bio_null", "true", &QueryCondition{Field: "bio", Operator: "IS NULL"}, false},
+		{"bio_null", "false", &QueryCondition{Field: "bio", Operator: "IS NOT NULL"}, false},
+		{"bio_null", "maybe", nil, true},
+		{"author.name_like", "tom", &QueryCondition{Field: "author.name", Value: "tom", Operator: "LIKE"}, false},
+		{"", "tom", nil, true},
+		{"name", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field+"="+tt.value, func(t *testing.T) {
+			got, err := ParseQueryConditionFromUrlParam(tt.field, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryGroupsFromUrlParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   map[string][]string
+		want    []*QueryGroup
+		wantErr bool
+	}{
+		{
+			name:  "or",
+			query: map[string][]string{"or": {"(name_like:tom,bio_null:true)"}},
+			want: []*QueryGroup{{Logic: "OR", Conditions: []*QueryCondition{
+				{Field: "name", Value: "tom", Operator: "LIKE"},
+				{Field: "bio", Operator: "IS NULL"},
+			}}},
+		},
+		{
+			name:  "not",
+			query: map[string][]string{"not": {"(name:tom,bio:x)"}},
+			want: []*QueryGroup{{Logic: "AND", Not: true, Conditions: []*QueryCondition{
+				{Field: "name", Value: "tom", Operator: "="},
+				{Field: "bio", Value: "x", Operator: "="},
+			}}},
+		},
+		{
+			name:  "nested",
+			query: map[string][]string{"and": {"(id_gt:18,or(name:tom,not(name:jerry)))"}},
+			want: []*QueryGroup{{
+				Logic:      "AND",
+				Conditions: []*QueryCondition{{Field: "id", Value: "18", Operator: ">"}},
+				Groups: []*QueryGroup{{
+					Logic:      "OR",
+					Conditions: []*QueryCondition{{Field: "name", Value: "tom", Operator: "="}},
+					Groups: []*QueryGroup{{Logic: "AND", Not: true, Conditions: []*QueryCondition{
+						{Field: "name", Value: "jerry", Operator: "="},
+					}}},
+				}},
+			}},
+		},
+		{
+			name:  "list values and quoted values",
+			query: map[string][]string{"or": {`(id_in:(1,2,3),name:"a,(b)")`}},
+			want: []*QueryGroup{{Logic: "OR", Conditions: []*QueryCondition{
+				{Field: "id", Value: []interface{}{"1", "2", "3"}, Operator: "IN"},
+				{Field: "name", Value: "a,(b)", Operator: "="},
+			}}},
+		},
+		{
+			name:  "groups keep the and, or, not order",
+			query: map[string][]string{"not": {"(name:c)"}, "or": {"(name:b)"}, "and": {"(name:a)"}},
+			want: []*QueryGroup{
+				{Logic: "AND", Conditions: []*QueryCondition{{Field: "name", Value: "a", Operator: "="}}},
+				{Logic: "OR", Conditions: []*QueryCondition{{Field: "name", Value: "b", Operator: "="}}},
+				{Logic: "AND", Not: true, Conditions: []*QueryCondition{{Field: "name", Value: "c", Operator: "="}}},
+			},
+		},
+		{name: "too deep", query: map[string][]string{"and": {"(and(and(and(and(and(name:x))))))"}}, wantErr: true},
+		{name: "unbalanced", query: map[string][]string{"or": {"(name:tom,or(name:x)"}}, wantErr: true},
+		{name: "empty group", query: map[string][]string{"or": {"()"}}, wantErr: true},
+		{name: "missing value", query: map[string][]string{"or": {"(name)"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQueryGroupsFromUrlParams(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %s, want %s", describeGroups(got), describeGroups(tt.want))
+			}
+		})
+	}
+}
+
+// describeGroups 展开分组中的指针,便于在失败信息中比较
+func describeGroups(groups []*QueryGroup) string {
+	var s string
+	for _, group := range groups {
+		s += "{" + group.Logic
+		if group.Not {
+			s += " NOT"
+		}
+		for _, condition := range group.Conditions {
+			s += " " + condition.Field + " " + condition.Operator
+		}
+		s += " " + describeGroups(group.Groups) + "}"
+	}
+	return "[" + s + "]"
+}
+
+func TestParseSortKeys(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []SortKey
+		wantErr bool
+	}{
+		{"name", []SortKey{{Field: "name", Order: "ASC"}}, false},
+		{"-created_at,name", []SortKey{{Field: "created_at", Order: "DESC"}, {Field: "name", Order: "ASC"}}, false},
+		{" +name , -id ,", []SortKey{{Field: "name", Order: "ASC"}, {Field: "id", Order: "DESC"}}, false},
+		{"", nil, false},
+		{"-", nil, true},
+		{"name,-name", nil, true},
+		{"a,b,c,d,e,f", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSortKeys(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,229 +8,730 @@ import (
 	"log"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// ErrNotFound 按ID或过滤条件查询单条记录时记录不存在
+var ErrNotFound = errors.New("record not found")
+
 // buildBaseSelect 构建基本的SELECT查询语句
-func buildBaseSelect(tableName string) string {
-	return fmt.Sprintf("SELECT * FROM `%s`", tableName)
+func buildBaseSelect(d Dialect, tableName string) string {
+	return fmt.Sprintf("SELECT * FROM %s", d.Quote(tableName))
+}
+
+// buildBaseCount 构建基本的COUNT查询语句
+func buildBaseCount(d Dialect, tableName string) string {
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s", d.Quote(tableName))
 }
 
-// buildBaseUpdate 构建基本的UPDATE查询语句
-func buildBaseUpdate(table ITableUpdate) (string, []interface{}, error) {
-	v := reflect.ValueOf(table).Elem()
+// buildBaseUpdate 构建基本的UPDATE查询语句,只更新非零值字段,列名取自db标签
+// 版本号列不接受客户端赋值,包含版本号列的表每次更新自增版本号
+func buildBaseUpdate(d Dialect, table ITableUpdate) (string, []interface{}, error) {
+	v := reflect.Indirect(reflect.ValueOf(table))
 	t := v.Type()
 	var placeholders []string
+	var updatedColumns []string
 	var args []interface{}
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
 		if !field.IsZero() {
-			fieldName := t.Field(i).Name
-			if fieldName == "Id" {
+			column := strings.Split(t.Field(i).Tag.Get("db"), ",")[0]
+			if column == "" || column == "-" || column == "id" || column == VersionColumn {
 				continue
 			}
-			placeholders = append(placeholders, fmt.Sprintf("`%s` = ?", fieldName))
+			placeholders = append(placeholders, d.Quote(column)+" = ?")
+			updatedColumns = append(updatedColumns, column)
 			args = append(args, field.Interface())
 		}
 	}
 	if len(placeholders) == 0 {
 		return "", nil, errors.New("没有可更新的列")
 	}
-	query := fmt.Sprintf("UPDATE `%s` SET %s", table.TableName(), strings.Join(placeholders, ", "))
+	// 自动维护更新时间
+	if hasColumn(table.Columns(), UpdatedAtColumn) && !hasColumn(updatedColumns, UpdatedAtColumn) {
+		placeholders = append(placeholders, d.Quote(UpdatedAtColumn)+" = ?")
+		args = append(args, time.Now())
+	}
+	if HasVersion(table) {
+		placeholders = append(placeholders, versionIncrement(d, table.TableName()))
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s", d.Quote(table.TableName()), strings.Join(placeholders, ", "))
 	return query, args, nil
 }
 
+// insertValues 按Columns()顺序读取待插入的列和值,id为0时交由数据库自增生成
+func insertValues(table ITable) ([]string, []interface{}, error) {
+	columns := insertColumns(table, table.GetId() != 0)
+	values, err := insertRow(table, columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	return columns, values, nil
+}
+
+// insertColumns 返回插入时使用的列,withId为false时排除id列
+func insertColumns(table ITable, withId bool) []string {
+	var columns []string
+	for _, column := range table.Columns() {
+		if column == "id" && !withId {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// insertRow 按列名读取记录中对应字段的值,未赋值的创建/更新时间列填充当前时间
+func insertRow(table ITable, columns []string) ([]interface{}, error) {
+	v := reflect.Indirect(reflect.ValueOf(table))
+	now := time.Now()
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		field := columnMapper.FieldByName(v, column)
+		if !field.IsValid() {
+			return nil, fmt.Errorf("column %s not found in %T", column, table)
+		}
+		if value, ok := insertTimestamp(column, field, now); ok {
+			values[i] = value
+			continue
+		}
+		values[i] = field.Interface()
+	}
+	return values, nil
+}
+
+// rowsAffected 读取执行结果影响的行数
+func rowsAffected(result sql.Result) (int64, error) {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected, nil
+}
+
 // buildBaseDelete 构建基本的DELETE查询语句
-func buildBaseDelete(tableName string) string {
-	return fmt.Sprintf("DELETE FROM `%s`", tableName)
+func buildBaseDelete(d Dialect, tableName string) string {
+	return fmt.Sprintf("DELETE FROM %s", d.Quote(tableName))
+}
+
+// buildDeleteById 构建按ID删除的语句前缀,声明了软删除列的表改为写入删除时间
+func buildDeleteById(d Dialect, table ITable) (string, []interface{}) {
+	if column, ok := softDeleteColumn(table); ok {
+		return fmt.Sprintf("UPDATE %s SET %s = ?", d.Quote(table.TableName()), d.Quote(column)), []interface{}{time.Now()}
+	}
+	return buildBaseDelete(d, table.TableName()), nil
+}
+
+// whereId 构建按ID匹配单条记录的WHERE子句,方言支持时追加LIMIT 1
+func whereId(d Dialect, table any) string {
+	clause := " WHERE " + d.Quote("id") + " = ?" + andSoftDeleteClause(d, table)
+	if d.WriteLimit() {
+		clause += " LIMIT 1"
+	}
+	return clause
+}
+
+// whereIds 构建按ID列表匹配记录的WHERE子句,IN (?)需要经过sqlx.In展开
+func whereIds(d Dialect, table any) string {
+	return " WHERE " + d.Quote("id") + " IN (?)" + andSoftDeleteClause(d, table)
+}
+
+// andSoftDeleteClause 返回追加在WHERE条件后的软删除过滤条件
+func andSoftDeleteClause(d Dialect, table any) string {
+	if scope := softDeleteClause(d, table, ExcludeDeleted); scope != "" {
+		return " AND " + scope
+	}
+	return ""
+}
+
+// execContext 将?占位符改写为连接驱动使用的形式后执行语句
+func execContext(ctx context.Context, db DBTX, query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(ctx, db.Rebind(query), args...)
+}
+
+// selectRows 执行查询并将结果扫描为与table相同类型的记录
+func selectRows(ctx context.Context, db DBTX, table ITable, query string, args ...interface{}) ([]ITable, error) {
+	rows, err := db.QueryxContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	recordType := reflect.TypeOf(table)
+	records := make([]ITable, 0)
+	for rows.Next() {
+		record := reflect.New(recordType)
+		if err := rows.StructScan(record.Interface()); err != nil {
+			return nil, err
+		}
+		records = append(records, record.Elem().Interface().(ITable))
+	}
+	return records, rows.Err()
+}
+
+// getRow 执行查询并将第一行扫描为与table相同类型的记录
+func getRow(ctx context.Context, db DBTX, table ITable, query string, args ...interface{}) (ITable, error) {
+	record := reflect.New(reflect.TypeOf(table))
+	if err := db.QueryRowxContext(ctx, db.Rebind(query), args...).StructScan(record.Interface()); err != nil {
+		return nil, err
+	}
+	return record.Elem().Interface().(ITable), nil
 }
 
-// FindAll_mysql 查询表中的所有记录
-func FindAll_mysql(ctx context.Context, db *sqlx.DB, table ITable) ([]ITable, error) {
-	query := fmt.Sprintf("SELECT * FROM `%s`", table.TableName())
-	var rows []ITable
-	if err := db.SelectContext(ctx, &rows, query); err != nil {
+// FindAll 查询表中的所有记录,不包含已软删除的记录
+func (r *Repository) FindAll(ctx context.Context, table ITable) ([]ITable, error) {
+	d := r.dialect
+	query := buildBaseSelect(d, table.TableName())
+	if scope := softDeleteClause(d, table, ExcludeDeleted); scope != "" {
+		query += " WHERE " + scope
+	}
+	rows, err := selectRows(ctx, r.db, table, query)
+	if err != nil {
 		log.Printf("failed to select rows, sql: %s, error: %v", query, err)
-		return nil, fmt.Errorf("failed to select rows: %w", err)
+		return nil, fmt.Errorf("failed to select rows: %w", dbError(err))
 	}
 	return rows, nil
 }
 
-// FindOneById_mysql 根据ID查询单条记录
-func FindOneById_mysql(ctx context.Context, db *sqlx.DB, table ITable, id int64) (ITable, error) {
-	query := fmt.Sprintf("SELECT * FROM `%s` WHERE `id` = ?", table.TableName())
-	var record ITable
-	if err := db.GetContext(ctx, &record, query, id); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+// FindOneById 根据ID查询单条记录,记录不存在时返回ErrNotFound
+func (r *Repository) FindOneById(ctx context.Context, table ITable, id int64, fieldFilter *FieldFilter) (ITable, error) {
+	d := r.dialect
+	query, _, err := BuildSelectWithFieldFilter(d, table, fieldFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	query += " WHERE " + d.Quote("id") + " = ?" + andSoftDeleteClause(d, table)
+	record, err := getRow(ctx, r.db, table, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
 		}
 		log.Printf("failed to get row by id, sql: %s, id: %d, error: %v", query, id, err)
-		return nil, fmt.Errorf("failed to get row by id: %w", err)
+		return nil, fmt.Errorf("failed to get row by id: %w", dbError(err))
 	}
 	return record, nil
 }
 
-// FindSomeByIds_mysql 根据ID列表批量查询记录
-func FindSomeByIds_mysql(ctx context.Context, db *sqlx.DB, table ITable, ids []int64) ([]ITable, error) {
+// FindSomeByIds 根据ID列表批量查询记录
+func (r *Repository) FindSomeByIds(ctx context.Context, table ITable, ids []int64, fieldFilter *FieldFilter) ([]ITable, error) {
 	if len(ids) == 0 {
 		return nil, errors.New("ids is empty")
 	}
-	query, args, err := sqlx.In(fmt.Sprintf("SELECT * FROM `%s` WHERE `id` IN (?)", table.TableName()), ids)
+	d := r.dialect
+	query, _, err := BuildSelectWithFieldFilter(d, table, fieldFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	query, args, err := sqlx.In(query+whereIds(d, table), ids)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build IN query: %w", err)
 	}
-	query = db.Rebind(query)
-	var records []ITable
-	if err := db.SelectContext(ctx, &records, query, args...); err != nil {
+	records, err := selectRows(ctx, r.db, table, query, args...)
+	if err != nil {
 		log.Printf("failed to select rows by ids, sql: %s, args: %v, error: %v", query, args, err)
-		return nil, fmt.Errorf("failed to select rows by ids: %w", err)
+		return nil, fmt.Errorf("failed to select rows by ids: %w", dbError(err))
 	}
 	return records, nil
 }
 
-// FindSomeByFilter_mysql 使用过滤条件查询多条记录
-func FindSomeByFilter_mysql(ctx context.Context, db *sqlx.DB, table ITable, filter *QueryFilter) ([]ITable, error) {
-	query, args, err := CreateQuerySqlWithFilter(table, filter)
+// FindSomeByFilter 使用过滤条件查询多条记录
+func (r *Repository) FindSomeByFilter(ctx context.Context, table ITable, filter *QueryFilter, fieldFilter *FieldFilter) ([]ITable, error) {
+	query, args, err := CreateQuerySqlWithFilter(r.dialect, r.TableOf, table, filter, fieldFilter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create filter query: %w", err)
 	}
-	var records []ITable
-	if err := db.SelectContext(ctx, &records, query, args...); err != nil {
+	records, err := selectRows(ctx, r.db, table, query, args...)
+	if err != nil {
 		log.Printf("failed to select rows with filter, sql: %s, args: %v, error: %v", query, args, err)
-		return nil, fmt.Errorf("failed to select rows with filter: %w", err)
+		return nil, fmt.Errorf("failed to select rows with filter: %w", dbError(err))
 	}
 	return records, nil
 }
 
-// FindOneByFilter_mysql 使用过滤条件查询单条记录
-func FindOneByFilter_mysql(ctx context.Context, db *sqlx.DB, table ITable, filter *QueryFilter) (ITable, error) {
+// FindOneByFilter 使用过滤条件查询单条记录,没有符合条件的记录时返回ErrNotFound
+func (r *Repository) FindOneByFilter(ctx context.Context, table ITable, filter *QueryFilter) (ITable, error) {
 	if filter == nil {
 		return nil, fmt.Errorf("filter is nil")
 	}
 	if filter.Limit != 1 {
 		filter.Limit = 1
 	}
-	query, args, err := CreateQuerySqlWithFilter(table, filter)
+	query, args, err := CreateQuerySqlWithFilter(r.dialect, r.TableOf, table, filter, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create filter query: %w", err)
 	}
-	var record ITable
-	if err := db.GetContext(ctx, &record, query, args...); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+	record, err := getRow(ctx, r.db, table, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
 		}
 		log.Printf("failed to get row with filter, sql: %s, args: %v, error: %v", query, args, err)
-		return nil, fmt.Errorf("failed to get row with filter: %w", err)
+		return nil, fmt.Errorf("failed to get row with filter: %w", dbError(err))
 	}
 	return record, nil
 }
 
-// CreateOne_mysql 创建新记录
-func CreateOne_mysql(ctx context.Context, db *sqlx.DB, table ITable) error {
-	columns := table.Columns()
+// CountByFilter 使用过滤条件统计记录数
+func (r *Repository) CountByFilter(ctx context.Context, table ITable, filter *QueryFilter) (int64, error) {
+	query, args, err := CreateCountSqlWithFilter(r.dialect, r.TableOf, table, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create count query: %w", err)
+	}
+	var total int64
+	if err := sqlx.GetContext(ctx, r.db, &total, r.db.Rebind(query), args...); err != nil {
+		log.Printf("failed to count rows with filter, sql: %s, args: %v, error: %v", query, args, err)
+		return 0, fmt.Errorf("failed to count rows with filter: %w", dbError(err))
+	}
+	return total, nil
+}
+
+// CreateOne 创建新记录,返回记录ID
+func (r *Repository) CreateOne(ctx context.Context, table ITable) (int64, error) {
+	columns, args, err := insertValues(table)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build insert query: %w", err)
+	}
+	d := r.dialect
 	placeholders := make([]string, len(columns))
 	quotedColumns := make([]string, len(columns))
 	for i, col := range columns {
-		placeholders[i] = ":" + col
-		quotedColumns[i] = "`" + col + "`"
+		placeholders[i] = "?"
+		quotedColumns[i] = d.Quote(col)
 	}
-	query := fmt.Sprintf("INSERT INTO `%s` (%s) VALUES (%s)",
-		table.TableName(),
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.Quote(table.TableName()),
 		strings.Join(quotedColumns, ", "),
 		strings.Join(placeholders, ", "))
-	if _, err := db.NamedExecContext(ctx, query, table); err != nil {
+	if d.Returning() {
+		return r.insertReturningId(ctx, query, args...)
+	}
+	result, err := execContext(ctx, r.db, query, args...)
+	if err != nil {
 		log.Printf("failed to create row, sql: %s, table: %+v, error: %v", query, table, err)
-		return fmt.Errorf("failed to create row: %w", err)
+		return 0, fmt.Errorf("failed to create row: %w", dbError(err))
+	}
+	if id := table.GetId(); id != 0 {
+		return id, nil
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
 	}
-	return nil
+	return id, nil
 }
 
-// UpdateOne_mysql 更新单条记录
-func UpdateOne_mysql(ctx context.Context, db *sqlx.DB, table ITableUpdate) error {
-	query, args, err := buildBaseUpdate(table)
+// CreateMany 使用多行INSERT批量创建记录,按占位符上限分批执行,返回记录ID
+// 记录的id需全部为0(自增)或全部指定;支持RETURNING的方言直接读取返回的ID,
+// 其余方言由LastInsertId推算,依赖数据库为单条多行INSERT分配连续ID:
+// MySQL要求innodb_autoinc_lock_mode为0或1,且auto_increment_increment为1,
+// innodb_autoinc_lock_mode=2(MySQL 8.0的默认值)下并发插入时ID可能不连续,返回的ID不可靠,
+// 此时应为记录指定id,或逐条调用CreateOne
+func (r *Repository) CreateMany(ctx context.Context, tables []ITable) ([]int64, error) {
+	columns, err := batchInsertColumns(tables)
 	if err != nil {
-		return fmt.Errorf("failed to build update query: %w", err)
+		return nil, err
 	}
-	query += " WHERE `id` = ? LIMIT 1"
+	d := r.dialect
+	withId := tables[0].GetId() != 0
+	ids := make([]int64, 0, len(tables))
+	for _, chunk := range chunkRows(d, tables, len(columns)) {
+		query, args, err := buildMultiInsert(d, chunk, columns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build insert query: %w", err)
+		}
+		if d.Returning() {
+			chunkIds, err := r.insertReturningIds(ctx, query, args...)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, chunkIds...)
+			continue
+		}
+		result, err := execContext(ctx, r.db, query, args...)
+		if err != nil {
+			log.Printf("failed to create rows, table: %s, rows: %d, error: %v", tables[0].TableName(), len(chunk), err)
+			return nil, fmt.Errorf("failed to create rows: %w", dbError(err))
+		}
+		if withId {
+			for _, table := range chunk {
+				ids = append(ids, table.GetId())
+			}
+			continue
+		}
+		lastId, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		firstId := d.FirstInsertId(lastId, int64(len(chunk)))
+		for i := range chunk {
+			ids = append(ids, firstId+int64(i))
+		}
+	}
+	return ids, nil
+}
+
+// insertReturningId 执行INSERT ... RETURNING id,返回生成的记录ID
+func (r *Repository) insertReturningId(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	query += " RETURNING " + r.dialect.Quote("id")
+	var id int64
+	if err := r.db.QueryRowxContext(ctx, r.db.Rebind(query), args...).Scan(&id); err != nil {
+		log.Printf("failed to create row, sql: %s, args: %v, error: %v", query, args, err)
+		return 0, fmt.Errorf("failed to create row: %w", dbError(err))
+	}
+	return id, nil
+}
+
+// insertReturningIds 执行多行INSERT ... RETURNING id,按返回顺序读取每行的ID
+func (r *Repository) insertReturningIds(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	query += " RETURNING " + r.dialect.Quote("id")
+	rows, err := r.db.QueryxContext(ctx, r.db.Rebind(query), args...)
+	if err != nil {
+		log.Printf("failed to create rows, sql: %s, error: %v", query, err)
+		return nil, fmt.Errorf("failed to create rows: %w", dbError(err))
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan returning id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to create rows: %w", dbError(err))
+	}
+	return ids, nil
+}
+
+// UpsertOne 插入记录,主键或唯一键冲突时更新updateColumns指定的列,返回影响的行数
+func (r *Repository) UpsertOne(ctx context.Context, table ITable, updateColumns []string) (int64, error) {
+	return r.UpsertMany(ctx, []ITable{table}, updateColumns)
+}
+
+// UpsertMany 批量插入记录,冲突时更新updateColumns指定的列,返回影响的行数
+// updateColumns为空时更新除id外的全部插入列
+// 影响的行数与数据库有关,MySQL中新插入的行计1,更新的行计2,值未变化的行计0
+func (r *Repository) UpsertMany(ctx context.Context, tables []ITable, updateColumns []string) (int64, error) {
+	columns, err := batchInsertColumns(tables)
+	if err != nil {
+		return 0, err
+	}
+	updates, err := upsertColumns(tables[0], columns, updateColumns)
+	if err != nil {
+		return 0, err
+	}
+	d := r.dialect
+	assignments := make([]string, len(updates))
+	for i, col := range updates {
+		assignments[i] = d.Quote(col) + " = " + d.Excluded(col)
+		if col == VersionColumn {
+			assignments[i] = versionIncrement(d, tables[0].TableName())
+		}
+	}
+	var total int64
+	for _, chunk := range chunkRows(d, tables, len(columns)) {
+		query, args, err := buildMultiInsert(d, chunk, columns)
+		if err != nil {
+			return 0, fmt.Errorf("failed to build upsert query: %w", err)
+		}
+		query += " " + d.OnConflictUpdate() + " " + strings.Join(assignments, ", ")
+		result, err := execContext(ctx, r.db, query, args...)
+		if err != nil {
+			log.Printf("failed to upsert rows, sql: %s, rows: %d, error: %v", query, len(chunk), err)
+			return 0, fmt.Errorf("failed to upsert rows: %w", dbError(err))
+		}
+		affected, err := rowsAffected(result)
+		if err != nil {
+			return 0, err
+		}
+		total += affected
+	}
+	return total, nil
+}
+
+// batchInsertColumns 校验批量插入的记录并返回插入列
+// 记录的id需全部为0或全部指定,保证每行的列一致
+func batchInsertColumns(tables []ITable) ([]string, error) {
+	if len(tables) == 0 {
+		return nil, errors.New("tables is empty")
+	}
+	withId := tables[0].GetId() != 0
+	for _, table := range tables {
+		if (table.GetId() != 0) != withId {
+			return nil, errors.New("ids must be either all set or all empty")
+		}
+	}
+	return insertColumns(tables[0], withId), nil
+}
+
+// upsertColumns 校验冲突时需要更新的列,为空时返回除id和创建时间外的全部插入列
+// 表包含更新时间列和版本号列时总是一并更新
+func upsertColumns(table ITable, columns []string, updateColumns []string) ([]string, error) {
+	if len(updateColumns) == 0 {
+		var updates []string
+		for _, col := range columns {
+			if col != "id" && col != CreatedAtColumn {
+				updates = append(updates, col)
+			}
+		}
+		if len(updates) == 0 {
+			return nil, errors.New("没有可更新的列")
+		}
+		return updates, nil
+	}
+	inserted := make(map[string]struct{}, len(columns))
+	for _, col := range columns {
+		inserted[col] = struct{}{}
+	}
+	for _, col := range updateColumns {
+		// 防止SQL注入,验证字段名是否在白名单中
+		if _, ok := table.ColumnsMap()[col]; !ok || col == "id" {
+			return nil, ErrInvalidField
+		}
+		if _, ok := inserted[col]; !ok {
+			return nil, ErrInvalidField
+		}
+	}
+	for _, col := range []string{UpdatedAtColumn, VersionColumn} {
+		if hasColumn(columns, col) && !hasColumn(updateColumns, col) {
+			updateColumns = append(updateColumns[:len(updateColumns):len(updateColumns)], col)
+		}
+	}
+	return updateColumns, nil
+}
+
+// chunkRows 按单条语句的占位符上限拆分批量记录
+func chunkRows(d Dialect, tables []ITable, columnCount int) [][]ITable {
+	chunkSize := d.MaxPlaceholders() / columnCount
+	var chunks [][]ITable
+	for start := 0; start < len(tables); start += chunkSize {
+		chunks = append(chunks, tables[start:min(start+chunkSize, len(tables))])
+	}
+	return chunks
+}
+
+// buildMultiInsert 构建多行INSERT语句
+func buildMultiInsert(d Dialect, tables []ITable, columns []string) (string, []interface{}, error) {
+	quotedColumns := make([]string, len(columns))
+	for i, col := range columns {
+		quotedColumns[i] = d.Quote(col)
+	}
+	rowPlaceholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	rows := make([]string, len(tables))
+	args := make([]interface{}, 0, len(tables)*len(columns))
+	for i, table := range tables {
+		values, err := insertRow(table, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		rows[i] = rowPlaceholder
+		args = append(args, values...)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		d.Quote(tables[0].TableName()),
+		strings.Join(quotedColumns, ", "),
+		strings.Join(rows, ", "))
+	return query, args, nil
+}
+
+// UpdateOne 更新单条记录,返回影响的行数
+// 包含版本号列的表需在table中携带当前版本号,版本号不一致时返回ErrVersionConflict
+func (r *Repository) UpdateOne(ctx context.Context, table ITableUpdate) (int64, error) {
+	d := r.dialect
+	query, args, err := buildBaseUpdate(d, table)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build update query: %w", err)
+	}
+	query += " WHERE " + d.Quote("id") + " = ?" + andSoftDeleteClause(d, table)
 	args = append(args, table.GetId())
-	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+	versioned := HasVersion(table)
+	if versioned {
+		version, ok := VersionOf(table)
+		if !ok {
+			return 0, ErrVersionRequired
+		}
+		query += " AND " + d.Quote(VersionColumn) + " = ?"
+		args = append(args, version)
+	}
+	if d.WriteLimit() {
+		query += " LIMIT 1"
+	}
+	result, err := execContext(ctx, r.db, query, args...)
+	if err != nil {
 		log.Printf("failed to execute update, sql: %s, args: %v, error: %v", query, args, err)
-		return fmt.Errorf("failed to execute update: %w", err)
+		return 0, fmt.Errorf("failed to execute update: %w", dbError(err))
+	}
+	affected, err := rowsAffected(result)
+	if err != nil || affected > 0 || !versioned {
+		return affected, err
 	}
-	return nil
+	return 0, r.versionConflict(ctx, table)
 }
 
-// UpdateSomeByIds_mysql 更新单条记录
-func UpdateSomeByIds_mysql(ctx context.Context, db *sqlx.DB, table ITableUpdate, ids []int64) error {
-	query, args, err := buildBaseUpdate(table)
+// UpdateSomeByIds 根据ID列表批量更新记录,返回影响的行数
+func (r *Repository) UpdateSomeByIds(ctx context.Context, table ITableUpdate, ids []int64) (int64, error) {
+	d := r.dialect
+	query, args, err := buildBaseUpdate(d, table)
 	if err != nil {
-		return fmt.Errorf("failed to build update query: %w", err)
+		return 0, fmt.Errorf("failed to build update query: %w", err)
 	}
-	query, args, err = sqlx.In(query+" WHERE `id` IN (?)", ids)
+	query, args, err = sqlx.In(query+whereIds(d, table), append(args, ids)...)
 	if err != nil {
-		return fmt.Errorf("failed to build IN query: %w", err)
+		return 0, fmt.Errorf("failed to build IN query: %w", err)
 	}
-	query = db.Rebind(query)
-	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+	result, err := execContext(ctx, r.db, query, args...)
+	if err != nil {
 		log.Printf("failed to execute update, sql: %s, args: %v, error: %v", query, args, err)
-		return fmt.Errorf("failed to execute update: %w", err)
+		return 0, fmt.Errorf("failed to execute update: %w", dbError(err))
 	}
-	return nil
+	return rowsAffected(result)
 }
 
-// UpdateSomeByFilter_mysql 使用过滤条件更新记录
-func UpdateSomeByFilter_mysql(ctx context.Context, db *sqlx.DB, table ITable, tableUpdate ITableUpdate, filter *QueryFilter) error {
+// UpdateSomeByFilter 使用过滤条件更新记录,返回影响的行数
+func (r *Repository) UpdateSomeByFilter(ctx context.Context, table ITable, tableUpdate ITableUpdate, filter *QueryFilter) (int64, error) {
 	if filter == nil {
-		return fmt.Errorf("filter is nil")
+		return 0, fmt.Errorf("filter is nil")
 	}
-	query, args, err := CreateUpdateSqlWithFilter(table, tableUpdate, filter)
+	query, args, err := CreateUpdateSqlWithFilter(r.dialect, r.TableOf, table, tableUpdate, filter)
 	if err != nil {
-		return fmt.Errorf("failed to create filter update query: %w", err)
+		return 0, fmt.Errorf("failed to create filter update query: %w", err)
 	}
-	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+	result, err := execContext(ctx, r.db, query, args...)
+	if err != nil {
 		log.Printf("failed to execute update with filter, sql: %s, args: %v, error: %v", query, args, err)
-		return fmt.Errorf("failed to execute update with filter: %w", err)
+		return 0, fmt.Errorf("failed to execute update with filter: %w", dbError(err))
 	}
-	return nil
+	return rowsAffected(result)
 }
 
-// DeleteOneById_mysql 删除单条记录
-func DeleteOneById_mysql(ctx context.Context, db *sqlx.DB, table ITable, id int64) error {
-	query := fmt.Sprintf("DELETE FROM `%s` WHERE `id` = ? LIMIT 1", table.TableName())
-	if _, err := db.ExecContext(ctx, query, id); err != nil {
+// DeleteOneById 删除单条记录,声明了软删除列的表只写入删除时间,返回影响的行数
+func (r *Repository) DeleteOneById(ctx context.Context, table ITable, id int64) (int64, error) {
+	d := r.dialect
+	query, args := buildDeleteById(d, table)
+	query += whereId(d, table)
+	args = append(args, id)
+	result, err := execContext(ctx, r.db, query, args...)
+	if err != nil {
 		log.Printf("failed to delete row by id, sql: %s, id: %d, error: %v", query, id, err)
-		return fmt.Errorf("failed to delete row by id: %w", err)
+		return 0, fmt.Errorf("failed to delete row by id: %w", dbError(err))
 	}
-	return nil
+	return rowsAffected(result)
 }
 
-// DeleteSomeByIds_mysql 根据ID列表批量删除记录
-func DeleteSomeByIds_mysql(ctx context.Context, db *sqlx.DB, table ITable, ids []int64) error {
+// DeleteSomeByIds 根据ID列表批量删除记录,声明了软删除列的表只写入删除时间,返回影响的行数
+func (r *Repository) DeleteSomeByIds(ctx context.Context, table ITable, ids []int64) (int64, error) {
 	if len(ids) == 0 {
-		return errors.New("ids is empty")
+		return 0, errors.New("ids is empty")
 	}
-	query, args, err := sqlx.In(fmt.Sprintf("DELETE FROM `%s` WHERE `id` IN (?)", table.TableName()), ids)
+	d := r.dialect
+	query, args := buildDeleteById(d, table)
+	query, args, err := sqlx.In(query+whereIds(d, table), append(args, ids)...)
 	if err != nil {
-		return fmt.Errorf("failed to build IN query: %w", err)
+		return 0, fmt.Errorf("failed to build IN query: %w", err)
 	}
-	query = db.Rebind(query)
-	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+	result, err := execContext(ctx, r.db, query, args...)
+	if err != nil {
 		log.Printf("failed to delete rows by ids, sql: %s, args: %v, error: %v", query, args, err)
-		return fmt.Errorf("failed to delete rows by ids: %w", err)
+		return 0, fmt.Errorf("failed to delete rows by ids: %w", dbError(err))
 	}
-	return nil
+	return rowsAffected(result)
 }
 
-// DeleteSomeByFilter_mysql 使用过滤条件删除记录
-func DeleteSomeByFilter_mysql(ctx context.Context, db *sqlx.DB, table ITable, filter *QueryFilter) error {
-	query, args, err := CreateDeleteSqlWithFilter(table, filter)
+// DeleteSomeByFilter 使用过滤条件删除记录,声明了软删除列的表只写入删除时间,返回影响的行数
+func (r *Repository) DeleteSomeByFilter(ctx context.Context, table ITable, filter *QueryFilter) (int64, error) {
+	query, args, err := CreateDeleteSqlWithFilter(r.dialect, r.TableOf, table, filter)
 	if err != nil {
-		return fmt.Errorf("failed to create filter delete query: %w", err)
+		return 0, fmt.Errorf("failed to create filter delete query: %w", err)
 	}
-	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+	result, err := execContext(ctx, r.db, query, args...)
+	if err != nil {
 		log.Printf("failed to delete rows with filter, sql: %s, args: %v, error: %v", query, args, err)
-		return fmt.Errorf("failed to delete rows with filter: %w", err)
+		return 0, fmt.Errorf("failed to delete rows with filter: %w", dbError(err))
 	}
-	return nil
+	return rowsAffected(result)
+}
+
+// 以下函数在连接或事务上执行对应的Repository方法,按连接的驱动名选择方言
+// 新代码应通过Repository或Model派生的Table[T]调用
+
+// FindAll_mysql 见Repository.FindAll
+func FindAll_mysql(ctx context.Context, db DBTX, table ITable) ([]ITable, error) {
+	return repositoryOf(db).FindAll(ctx, table)
+}
+
+// FindOneById_mysql 见Repository.FindOneById
+func FindOneById_mysql(ctx context.Context, db DBTX, table ITable, id int64, fieldFilter *FieldFilter) (ITable, error) {
+	return repositoryOf(db).FindOneById(ctx, table, id, fieldFilter)
+}
+
+// FindSomeByIds_mysql 见Repository.FindSomeByIds
+func FindSomeByIds_mysql(ctx context.Context, db DBTX, table ITable, ids []int64, fieldFilter *FieldFilter) ([]ITable, error) {
+	return repositoryOf(db).FindSomeByIds(ctx, table, ids, fieldFilter)
+}
+
+// FindSomeByFilter_mysql 见Repository.FindSomeByFilter
+func FindSomeByFilter_mysql(ctx context.Context, db DBTX, table ITable, filter *QueryFilter, fieldFilter *FieldFilter) ([]ITable, error) {
+	return repositoryOf(db).FindSomeByFilter(ctx, table, filter, fieldFilter)
+}
+
+// FindOneByFilter_mysql 见Repository.FindOneByFilter
+func FindOneByFilter_mysql(ctx context.Context, db DBTX, table ITable, filter *QueryFilter) (ITable, error) {
+	return repositoryOf(db).FindOneByFilter(ctx, table, filter)
+}
+
+// CountByFilter_mysql 见Repository.CountByFilter
+func CountByFilter_mysql(ctx context.Context, db DBTX, table ITable, filter *QueryFilter) (int64, error) {
+	return repositoryOf(db).CountByFilter(ctx, table, filter)
+}
+
+// CreateOne_mysql 见Repository.CreateOne
+func CreateOne_mysql(ctx context.Context, db DBTX, table ITable) (int64, error) {
+	return repositoryOf(db).CreateOne(ctx, table)
+}
+
+// CreateMany_mysql 见Repository.CreateMany
+func CreateMany_mysql(ctx context.Context, db DBTX, tables []ITable) ([]int64, error) {
+	return repositoryOf(db).CreateMany(ctx, tables)
+}
+
+// UpsertOne_mysql 见Repository.UpsertOne
+func UpsertOne_mysql(ctx context.Context, db DBTX, table ITable, updateColumns []string) (int64, error) {
+	return repositoryOf(db).UpsertOne(ctx, table, updateColumns)
+}
+
+// UpsertMany_mysql 见Repository.UpsertMany
+func UpsertMany_mysql(ctx context.Context, db DBTX, tables []ITable, updateColumns []string) (int64, error) {
+	return repositoryOf(db).UpsertMany(ctx, tables, updateColumns)
+}
+
+// UpdateOne_mysql 见Repository.UpdateOne
+func UpdateOne_mysql(ctx context.Context, db DBTX, table ITableUpdate) (int64, error) {
+	return repositoryOf(db).UpdateOne(ctx, table)
+}
+
+// UpdateSomeByIds_mysql 见Repository.UpdateSomeByIds
+func UpdateSomeByIds_mysql(ctx context.Context, db DBTX, table ITableUpdate, ids []int64) (int64, error) {
+	return repositoryOf(db).UpdateSomeByIds(ctx, table, ids)
+}
+
+// UpdateSomeByFilter_mysql 见Repository.UpdateSomeByFilter
+func UpdateSomeByFilter_mysql(ctx context.Context, db DBTX, table ITable, tableUpdate ITableUpdate, filter *QueryFilter) (int64, error) {
+	return repositoryOf(db).UpdateSomeByFilter(ctx, table, tableUpdate, filter)
+}
+
+// DeleteOneById_mysql 见Repository.DeleteOneById
+func DeleteOneById_mysql(ctx context.Context, db DBTX, table ITable, id int64) (int64, error) {
+	return repositoryOf(db).DeleteOneById(ctx, table, id)
+}
+
+// DeleteSomeByIds_mysql 见Repository.DeleteSomeByIds
+func DeleteSomeByIds_mysql(ctx context.Context, db DBTX, table ITable, ids []int64) (int64, error) {
+	return repositoryOf(db).DeleteSomeByIds(ctx, table, ids)
+}
+
+// DeleteSomeByFilter_mysql 见Repository.DeleteSomeByFilter
+func DeleteSomeByFilter_mysql(ctx context.Context, db DBTX, table ITable, filter *QueryFilter) (int64, error) {
+	return repositoryOf(db).DeleteSomeByFilter(ctx, table, filter)
 }
@@ -0,0 +1,420 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrUnknownRelation include中的关联未在表上声明
+var ErrUnknownRelation = errors.New("unknown relation")
+
+// IRelations 声明与其他表的关联,键为关联名称(include参数和返回结果中使用),值为关联规则
+// 规则以逗号分隔,格式与校验规则一致:
+//
+//	belongs_to=authors,foreign_key=author_id                                 本表的author_id引用authors.id
+//	has_many=books,foreign_key=author_id                                     books.author_id引用本表的id
+//	many_to_many=tags,through=book_tags,foreign_key=book_id,references=tag_id 通过中间表book_tags关联tags
+type IRelations interface {
+	Relations() map[string]string // 获取关联规则
+}
+
+// RelationKind 关联类型
+type RelationKind string
+
+const (
+	BelongsTo  RelationKind = "belongs_to"   // 多对一,本表的外键引用关联表的id
+	HasMany    RelationKind = "has_many"     // 一对多,关联表的外键引用本表的id
+	ManyToMany RelationKind = "many_to_many" // 多对多,通过中间表关联
+)
+
+// Relation 解析后的关联规则
+type Relation struct {
+	Name       string       // 关联名称
+	Kind       RelationKind // 关联类型
+	Table      string       // 关联表名
+	ForeignKey string       // belongs_to为本表的外键列,has_many为关联表的外键列,many_to_many为中间表中引用本表的列
+	Through    string       // many_to_many的中间表
+	References string       // many_to_many中间表中引用关联表的列
+}
+
+// ParseRelation 解析名为name的关联规则
+func ParseRelation(name string, rule string) (*Relation, error) {
+	relation := &Relation{Name: name}
+	for _, item := range strings.Split(rule, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch RelationKind(key) {
+		case BelongsTo, HasMany, ManyToMany:
+			if relation.Kind != "" {
+				return nil, fmt.Errorf("relation %s declares more than one kind", name)
+			}
+			relation.Kind, relation.Table = RelationKind(key), value
+			continue
+		}
+		switch key {
+		case "foreign_key":
+			relation.ForeignKey = value
+		case "through":
+			relation.Through = value
+		case "references":
+			relation.References = value
+		default:
+			return nil, fmt.Errorf("invalid rule %q in relation %s", item, name)
+		}
+	}
+	if relation.Kind == "" || relation.Table == "" || relation.ForeignKey == "" {
+		return nil, fmt.Errorf("relation %s requires a kind, a table and a foreign_key", name)
+	}
+	if relation.Kind == ManyToMany && (relation.Through == "" || relation.References == "") {
+		return nil, fmt.Errorf("relation %s requires through and references", name)
+	}
+	return relation, nil
+}
+
+// RelationsOf 获取表声明的全部关联,未声明时返回nil
+func RelationsOf(table ITable) (map[string]*Relation, error) {
+	declared, ok := table.(IRelations)
+	if !ok {
+		return nil, nil
+	}
+	relations := make(map[string]*Relation, len(declared.Relations()))
+	for name, rule := range declared.Relations() {
+		relation, err := ParseRelation(name, rule)
+		if err != nil {
+			return nil, err
+		}
+		relations[name] = relation
+	}
+	return relations, nil
+}
+
+// RelationNames 获取表声明的关联名称,按名称排序
+func RelationNames(table ITable) []string {
+	declared, ok := table.(IRelations)
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(declared.Relations()))
+	for name := range declared.Relations() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TableLookup 按表名查找表结构,构建按关联过滤的条件时使用,通常为Repository.TableOf
+type TableLookup func(name string) (ITable, error)
+
+// tableRegistry 句柄上注册的表结构,绑定事务的句柄副本共享同一注册表
+type tableRegistry struct {
+	mu     sync.RWMutex
+	tables map[string]ITable
+}
+
+func newTableRegistry() *tableRegistry {
+	return &tableRegistry{tables: make(map[string]ITable)}
+}
+
+// RegisterTable 注册表结构,加载关联和按关联过滤时按表名查找关联表的记录类型
+// 通过Model创建的模型会自动注册
+func (r *Repository) RegisterTable(table ITable) {
+	r.tables.mu.Lock()
+	defer r.tables.mu.Unlock()
+	r.tables.tables[table.TableName()] = table
+}
+
+// TableOf 根据表名获取在句柄上注册的表结构
+func (r *Repository) TableOf(name string) (ITable, error) {
+	r.tables.mu.RLock()
+	defer r.tables.mu.RUnlock()
+	table, ok := r.tables.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("table %s is not registered", name)
+	}
+	return table, nil
+}
+
+// RelationColumns 返回加载include中的关联时本表必须查询的列
+func RelationColumns(table ITable, include []string) ([]string, error) {
+	relations, err := RelationsOf(table)
+	if err != nil {
+		return nil, err
+	}
+	columns := []string{"id"}
+	for _, name := range include {
+		relation, ok := relations[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRelation, name)
+		}
+		if relation.Kind == BelongsTo {
+			columns = append(columns, relation.ForeignKey)
+		}
+	}
+	return columns, nil
+}
+
+// LoadRelations 为items批量加载include中的关联,关联表需已在句柄上注册,返回与items一一对应的关联数据,键为关联名称
+// 每个关联执行一次IN查询,多对多关联额外查询一次中间表,不会逐条查询
+// belongs_to的值为关联记录,不存在时为nil;has_many和many_to_many的值为关联记录列表
+func (r *Repository) LoadRelations(ctx context.Context, table ITable, items []ITable, include []string) ([]map[string]interface{}, error) {
+	relations, err := RelationsOf(table)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, len(items))
+	for i := range result {
+		result[i] = make(map[string]interface{}, len(include))
+	}
+	for _, name := range include {
+		relation, ok := relations[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRelation, name)
+		}
+		if len(items) == 0 {
+			continue
+		}
+		if _, loaded := result[0][name]; loaded {
+			continue
+		}
+		related, err := r.TableOf(relation.Table)
+		if err != nil {
+			return nil, err
+		}
+		switch relation.Kind {
+		case BelongsTo:
+			err = r.loadBelongsTo(ctx, relation, related, items, result)
+		case HasMany:
+			err = r.loadHasMany(ctx, relation, related, items, result)
+		case ManyToMany:
+			err = r.loadManyToMany(ctx, relation, related, items, result)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load relation %s: %w", name, err)
+		}
+	}
+	return result, nil
+}
+
+// loadBelongsTo 按本表的外键值批量查询关联记录
+func (r *Repository) loadBelongsTo(ctx context.Context, relation *Relation, related ITable, items []ITable, result []map[string]interface{}) error {
+	keys := make([]int64, len(items))
+	valid := make([]bool, len(items))
+	var ids []int64
+	for i, item := range items {
+		key, ok, err := int64Column(item, relation.ForeignKey)
+		if err != nil {
+			return err
+		}
+		keys[i], valid[i] = key, ok
+		if ok {
+			ids = append(ids, key)
+		}
+	}
+	byId, err := r.findRelatedByIds(ctx, related, ids)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		var value interface{}
+		if row, ok := byId[keys[i]]; valid[i] && ok {
+			value = row
+		}
+		result[i][relation.Name] = value
+	}
+	return nil
+}
+
+// loadHasMany 按本表的ID批量查询外键引用本表的关联记录
+func (r *Repository) loadHasMany(ctx context.Context, relation *Relation, related ITable, items []ITable, result []map[string]interface{}) error {
+	// 同一父记录的关联记录位于同一批次,合并后每个父记录的关联记录仍按id排序
+	var rows []ITable
+	for _, ids := range chunkIds(uniqueIds(itemIds(items))) {
+		filter := &QueryFilter{
+			Conditions: []*QueryCondition{{Field: relation.ForeignKey, Operator: "IN", Value: ids}},
+			Sorts:      []SortKey{{Field: "id", Order: "ASC"}},
+		}
+		chunk, err := r.FindSomeByFilter(ctx, related, filter, nil)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, chunk...)
+	}
+	if err := afterFindRows(ctx, rows); err != nil {
+		return err
+	}
+	byKey := make(map[int64][]ITable)
+	for _, row := range rows {
+		key, ok, err := int64Column(row, relation.ForeignKey)
+		if err != nil {
+			return err
+		}
+		if ok {
+			byKey[key] = append(byKey[key], row)
+		}
+	}
+	for i, item := range items {
+		result[i][relation.Name] = nonNilRows(byKey[item.GetId()])
+	}
+	return nil
+}
+
+// loadManyToMany 先查询中间表中的ID对,再批量查询关联记录
+func (r *Repository) loadManyToMany(ctx context.Context, relation *Relation, related ITable, items []ITable, result []map[string]interface{}) error {
+	targets := make(map[int64][]int64)
+	var targetIds []int64
+	for _, ids := range chunkIds(uniqueIds(itemIds(items))) {
+		if err := r.selectJoinRows(ctx, relation, ids, func(owner, target int64) {
+			targets[owner] = append(targets[owner], target)
+			targetIds = append(targetIds, target)
+		}); err != nil {
+			return err
+		}
+	}
+	byId, err := r.findRelatedByIds(ctx, related, targetIds)
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		var values []ITable
+		for _, target := range targets[item.GetId()] {
+			if record, ok := byId[target]; ok {
+				values = append(values, record)
+			}
+		}
+		result[i][relation.Name] = nonNilRows(values)
+	}
+	return nil
+}
+
+// selectJoinRows 查询中间表中引用ids的ID对,逐行交给fn
+func (r *Repository) selectJoinRows(ctx context.Context, relation *Relation, ids []int64, fn func(owner, target int64)) error {
+	d := r.dialect
+	query, args, err := sqlx.In(fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (?)",
+		d.Quote(relation.ForeignKey), d.Quote(relation.References), d.Quote(relation.Through), d.Quote(relation.ForeignKey)), ids)
+	if err != nil {
+		return fmt.Errorf("failed to build IN query: %w", err)
+	}
+	rows, err := r.db.QueryxContext(ctx, r.db.Rebind(query), args...)
+	if err != nil {
+		log.Printf("failed to select join rows, sql: %s, args: %v, error: %v", query, args, err)
+		return fmt.Errorf("failed to select join rows: %w", dbError(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var owner, target int64
+		if err := rows.Scan(&owner, &target); err != nil {
+			return err
+		}
+		fn(owner, target)
+	}
+	return rows.Err()
+}
+
+// findRelatedByIds 按ID分批查询关联记录并调用AfterFind钩子,返回以ID为键的记录
+func (r *Repository) findRelatedByIds(ctx context.Context, related ITable, ids []int64) (map[int64]ITable, error) {
+	byId := make(map[int64]ITable)
+	for _, chunk := range chunkIds(uniqueIds(ids)) {
+		rows, err := r.FindSomeByIds(ctx, related, chunk, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := afterFindRows(ctx, rows); err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			byId[row.GetId()] = row
+		}
+	}
+	return byId, nil
+}
+
+// chunkIds 按IN条件的值数量上限拆分ID列表
+func chunkIds(ids []int64) [][]int64 {
+	var chunks [][]int64
+	for start := 0; start < len(ids); start += maxInValues {
+		chunks = append(chunks, ids[start:min(start+maxInValues, len(ids))])
+	}
+	return chunks
+}
+
+// int64Column 按列名读取记录中的整数值,值为NULL时返回false
+func int64Column(item ITable, column string) (int64, bool, error) {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	fi, ok := columnMapper.TypeMap(v.Type()).Names[column]
+	if !ok {
+		return 0, false, fmt.Errorf("%w: %s", ErrInvalidField, column)
+	}
+	value := v.FieldByIndex(fi.Index).Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return 0, false, err
+		}
+	}
+	if value == nil {
+		return 0, false, nil
+	}
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.CanInt():
+		return rv.Int(), true, nil
+	case rv.CanUint():
+		return int64(rv.Uint()), true, nil
+	}
+	return 0, false, fmt.Errorf("column %s is not an integer", column)
+}
+
+// itemIds 获取记录的ID列表
+func itemIds(items []ITable) []int64 {
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.GetId()
+	}
+	return ids
+}
+
+// uniqueIds 去除重复的ID,保持原有顺序
+func uniqueIds(ids []int64) []int64 {
+	seen := make(map[int64]struct{}, len(ids))
+	result := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// nonNilRows 没有关联记录时返回空列表,序列化为[]而不是null
+func nonNilRows(rows []ITable) []ITable {
+	if rows == nil {
+		return []ITable{}
+	}
+	return rows
+}
+
+// afterFindRows 对关联记录逐条调用AfterFind钩子
+func afterFindRows(ctx context.Context, rows []ITable) error {
+	for i, row := range rows {
+		record := reflect.New(reflect.TypeOf(row))
+		record.Elem().Set(reflect.ValueOf(row))
+		hook, ok := record.Interface().(IAfterFind)
+		if !ok {
+			return nil
+		}
+		if err := hook.AfterFind(ctx); err != nil {
+			return err
+		}
+		rows[i] = record.Elem().Interface().(ITable)
+	}
+	return nil
+}
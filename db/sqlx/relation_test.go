@@ -0,0 +1,164 @@
+package sqlx
+
+import (
+	"context"
+	sqlc "crud/db/sqlc"
+	"fmt"
+	"testing"
+)
+
+// taggedPost 通过中间表关联标签的测试表
+type taggedPost struct {
+	ID    int64  `db:"id" json:"id"`
+	Title string `db:"title" json:"title"`
+}
+
+// postTag 标签测试表
+type postTag struct {
+	ID   int64  `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+}
+
+func (m taggedPost) TableName() string { return "tagged_posts" }
+func (m taggedPost) Columns() []string { return []string{"id", "title"} }
+func (m taggedPost) ColumnsMap() map[string]struct{} {
+	return map[string]struct{}{"id": {}, "title": {}}
+}
+func (m taggedPost) GetId() int64 { return m.ID }
+func (m taggedPost) Relations() map[string]string {
+	return map[string]string{"tags": "many_to_many=post_tags,through=tagged_post_tags,foreign_key=post_id,references=tag_id"}
+}
+
+func (m postTag) TableName() string               { return "post_tags" }
+func (m postTag) Columns() []string               { return []string{"id", "name"} }
+func (m postTag) ColumnsMap() map[string]struct{} { return map[string]struct{}{"id": {}, "name": {}} }
+func (m postTag) GetId() int64                    { return m.ID }
+
+// rowIds 返回关联记录的ID
+func rowIds(value interface{}) []int64 {
+	rows, _ := value.([]ITable)
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.GetId()
+	}
+	return ids
+}
+
+// 父记录数量超过IN条件的上限时分批查询,结果与单次查询一致
+func TestLoadRelationsBatches(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	for _, statement := range []string{
+		`CREATE TABLE tagged_posts (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL)`,
+		`CREATE TABLE post_tags (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL)`,
+		`CREATE TABLE tagged_post_tags (post_id INTEGER NOT NULL, tag_id INTEGER NOT NULL)`,
+	} {
+		if _, err := repo.DB().Exec(statement); err != nil {
+			t.Fatalf("create table: %v", err)
+		}
+	}
+	authors := Model[sqlc.Author](repo)
+	books := Model[sqlc.Book](repo)
+	posts := Model[taggedPost](repo)
+	tags := Model[postTag](repo)
+
+	count := maxInValues + 5
+	newAuthors := make([]sqlc.Author, count)
+	newPosts := make([]taggedPost, count)
+	for i := range newAuthors {
+		newAuthors[i] = sqlc.Author{Name: fmt.Sprintf("author %d", i)}
+		newPosts[i] = taggedPost{Title: fmt.Sprintf("post %d", i)}
+	}
+	authorIds, err := authors.CreateMany(ctx, newAuthors)
+	if err != nil {
+		t.Fatalf("create authors: %v", err)
+	}
+	postIds, err := posts.CreateMany(ctx, newPosts)
+	if err != nil {
+		t.Fatalf("create posts: %v", err)
+	}
+	tagIds, err := tags.CreateMany(ctx, []postTag{{Name: "go"}, {Name: "sql"}})
+	if err != nil {
+		t.Fatalf("create tags: %v", err)
+	}
+	// 第一个作者和文章没有关联记录,最后一个各有两条,位于第二批
+	newBooks := []sqlc.Book{{Title: "extra", AuthorID: authorIds[count-1]}}
+	for _, id := range authorIds[1:] {
+		newBooks = append(newBooks, sqlc.Book{Title: "book", AuthorID: id})
+	}
+	if _, err := books.CreateMany(ctx, newBooks); err != nil {
+		t.Fatalf("create books: %v", err)
+	}
+	err = repo.WithTx(ctx, func(tx *Tx) error {
+		for i, id := range postIds[1:] {
+			tagId := tagIds[i%2]
+			if _, err := tx.Sqlx().Exec(`INSERT INTO tagged_post_tags (post_id, tag_id) VALUES (?, ?)`, id, tagId); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Sqlx().Exec(`INSERT INTO tagged_post_tags (post_id, tag_id) VALUES (?, ?)`, postIds[count-1], tagIds[1])
+		return err
+	})
+	if err != nil {
+		t.Fatalf("create post tags: %v", err)
+	}
+
+	t.Run("has_many", func(t *testing.T) {
+		items, err := authors.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		relations, err := authors.LoadRelations(ctx, items, []string{"books"})
+		if err != nil {
+			t.Fatalf("LoadRelations: %v", err)
+		}
+		if got := len(rowIds(relations[0]["books"])); got != 0 {
+			t.Errorf("first author books = %d, want 0", got)
+		}
+		if got := len(rowIds(relations[maxInValues]["books"])); got != 1 {
+			t.Errorf("author %d books = %d, want 1", maxInValues, got)
+		}
+		// 同一作者的书按id排序,extra最先创建
+		last := relations[count-1]["books"].([]ITable)
+		if len(last) != 2 || last[0].(sqlc.Book).Title != "extra" {
+			t.Errorf("last author books = %+v, want extra and book", last)
+		}
+	})
+
+	t.Run("belongs_to", func(t *testing.T) {
+		items, err := books.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		relations, err := books.LoadRelations(ctx, items, []string{"author"})
+		if err != nil {
+			t.Fatalf("LoadRelations: %v", err)
+		}
+		for i, item := range items {
+			author, ok := relations[i]["author"].(sqlc.Author)
+			if !ok || author.ID != item.AuthorID {
+				t.Fatalf("book %d author = %+v, want id %d", item.ID, relations[i]["author"], item.AuthorID)
+			}
+		}
+	})
+
+	t.Run("many_to_many", func(t *testing.T) {
+		items, err := posts.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		relations, err := posts.LoadRelations(ctx, items, []string{"tags"})
+		if err != nil {
+			t.Fatalf("LoadRelations: %v", err)
+		}
+		if got := rowIds(relations[0]["tags"]); len(got) != 0 {
+			t.Errorf("first post tags = %v, want none", got)
+		}
+		if got := rowIds(relations[maxInValues+1]["tags"]); len(got) != 1 || got[0] != tagIds[maxInValues%2] {
+			t.Errorf("post %d tags = %v, want [%d]", maxInValues+1, got, tagIds[maxInValues%2])
+		}
+		if got := rowIds(relations[count-1]["tags"]); len(got) != 2 {
+			t.Errorf("last post tags = %v, want both tags", got)
+		}
+	})
+}
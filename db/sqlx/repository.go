@@ -0,0 +1,117 @@
+package sqlx
+
+import (
+	"context"
+	sqlc "crud/db/sqlc"
+	sqlcpg "crud/db/sqlc/postgres"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SqlcFactory 基于连接或事务创建sqlc查询实例
+// 各方言的sqlc包生成各自的Queries类型,例如 sqlc.New 与 postgres.New
+type SqlcFactory func(db sqlc.DBTX) any
+
+var sqlcFactories = map[string]SqlcFactory{
+	"mysql":    func(db sqlc.DBTX) any { return sqlc.New(db) },
+	"sqlite":   func(db sqlc.DBTX) any { return sqlc.New(db) },
+	"postgres": func(db sqlc.DBTX) any { return sqlcpg.New(db) },
+}
+
+// RegisterSqlc 注册方言默认使用的sqlc查询实例构造函数
+func RegisterSqlc(dialect string, factory SqlcFactory) {
+	sqlcFactories[dialect] = factory
+}
+
+// sqlcFactoryOf 返回方言默认的sqlc构造函数,未注册的方言使用MySQL的sqlc包
+func sqlcFactoryOf(d Dialect) SqlcFactory {
+	if factory, ok := sqlcFactories[d.Name()]; ok {
+		return factory
+	}
+	return sqlcFactories["mysql"]
+}
+
+// Repository 数据库句柄,持有同一连接上的sqlx和sqlc实例,通用CRUD以方法的形式在句柄上执行
+// 同一进程中可为多个数据库或schema分别创建,Table[T]通过Model从句柄派生
+type Repository struct {
+	db      DBTX           // 执行查询的连接或事务
+	conn    *sqlx.DB       // sqlx数据库连接,用于开启事务
+	tx      *Tx            // 当前绑定的事务,未绑定时为nil
+	dialect Dialect        // SQL方言
+	newSqlc SqlcFactory    // sqlc查询实例的构造函数,开启事务时使用
+	tables  *tableRegistry // 通过Model注册的表结构,加载关联和按关联过滤时使用
+	// Sqlc sqlc查询实例,类型由方言决定: MySQL和SQLite为 *sqlc.Queries, PostgreSQL为 *postgres.Queries
+	Sqlc any
+}
+
+// NewRepository 使用指定方言创建数据库句柄
+// 将 *sql.DB 转换为 *sqlx.DB 以支持更多功能,并按方言创建共享的sqlc查询实例
+func NewRepository(db *sql.DB, d Dialect) *Repository {
+	return NewRepositoryWithSqlc(db, d, sqlcFactoryOf(d))
+}
+
+// NewRepositoryWithSqlc 使用指定方言和sqlc构造函数创建数据库句柄
+// 用于方言默认的sqlc包不适用的场景,例如同一方言下的多套sqlc查询
+func NewRepositoryWithSqlc(db *sql.DB, d Dialect, newSqlc SqlcFactory) *Repository {
+	conn := sqlx.NewDb(db, d.Name())
+	return &Repository{
+		db:      conn,
+		conn:    conn,
+		dialect: d,
+		newSqlc: newSqlc,
+		tables:  newTableRegistry(),
+		Sqlc:    newSqlc(db),
+	}
+}
+
+// repositoryOf 为连接或事务创建临时句柄,方言按驱动名选择,供 _mysql 函数使用
+// 临时句柄上没有注册的表结构,不支持按关联过滤
+func repositoryOf(db DBTX) *Repository {
+	d := dialectOf(db)
+	r := &Repository{db: db, dialect: d, newSqlc: sqlcFactoryOf(d), tables: newTableRegistry()}
+	if conn, ok := db.(*sqlx.DB); ok {
+		r.conn = conn
+	}
+	return r
+}
+
+// bind 返回绑定到事务的句柄副本,通用CRUD和sqlc查询都在该事务上执行
+func (r *Repository) bind(tx *Tx) *Repository {
+	bound := *r
+	bound.db = tx.tx
+	bound.tx = tx
+	bound.Sqlc = tx.Sqlc
+	return &bound
+}
+
+// Model 从数据库句柄派生表T的数据库操作模型,模型共享句柄的sqlx和sqlc实例
+// 同时在句柄上注册表结构,供同一句柄上的其他表加载关联时使用
+func Model[T ITable](r *Repository) *Table[T] {
+	var tableInstance T
+	r.RegisterTable(tableInstance)
+	return &Table[T]{
+		table: tableInstance,
+		repo:  r,
+		Sqlc:  r.Sqlc,
+	}
+}
+
+// DB 返回底层的sqlx数据库连接
+func (r *Repository) DB() *sqlx.DB {
+	return r.conn
+}
+
+// Dialect 返回句柄使用的SQL方言
+func (r *Repository) Dialect() Dialect {
+	return r.dialect
+}
+
+// WithTx 在新事务中执行fn,可通过TxTable在事务中操作多个表
+// 句柄已绑定事务时使用保存点嵌套执行
+func (r *Repository) WithTx(ctx context.Context, fn func(tx *Tx) error) error {
+	if r.tx != nil {
+		return r.tx.WithTx(ctx, fn)
+	}
+	return withTx(ctx, r, fn)
+}
@@ -0,0 +1,134 @@
+package sqlx
+
+import (
+	"context"
+	"crud/db"
+	sqlc "crud/db/sqlc"
+	sqlcpg "crud/db/sqlc/postgres"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// customQueries 注入的sqlc查询实例
+type customQueries struct {
+	db sqlc.DBTX
+}
+
+func TestRepositorySqlc(t *testing.T) {
+	conn, err := db.NewSQLiteConnector(":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	tests := []struct {
+		name string
+		repo *Repository
+		want reflect.Type
+	}{
+		{"sqlite", NewRepository(conn, SQLite), reflect.TypeOf(&sqlc.Queries{})},
+		{"mysql", NewRepository(conn, MySQL), reflect.TypeOf(&sqlc.Queries{})},
+		{"postgres", NewRepository(conn, Postgres), reflect.TypeOf(&sqlcpg.Queries{})},
+		{"injected", NewRepositoryWithSqlc(conn, SQLite, func(db sqlc.DBTX) any { return &customQueries{db} }), reflect.TypeOf(&customQueries{})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reflect.TypeOf(tt.repo.Sqlc); got != tt.want {
+				t.Fatalf("Repository.Sqlc = %v, want %v", got, tt.want)
+			}
+			if got := reflect.TypeOf(Model[sqlc.Author](tt.repo).Sqlc); got != tt.want {
+				t.Fatalf("Table.Sqlc = %v, want %v", got, tt.want)
+			}
+			// 事务中的sqlc实例使用同一构造函数创建
+			err := tt.repo.WithTx(context.Background(), func(tx *Tx) error {
+				if got := reflect.TypeOf(tx.Sqlc); got != tt.want {
+					t.Errorf("Tx.Sqlc = %v, want %v", got, tt.want)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WithTx: %v", err)
+			}
+		})
+	}
+}
+
+func TestRepositoryMethods(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	id, err := repo.CreateOne(ctx, sqlc.Author{Name: "tom"})
+	if err != nil {
+		t.Fatalf("CreateOne: %v", err)
+	}
+	errRollback := errors.New("rollback")
+
+	err = repo.WithTx(ctx, func(tx *Tx) error {
+		bound := tx.Repository()
+		// 绑定事务的句柄上开启的事务使用保存点,回滚不影响外层事务
+		err := bound.WithTx(ctx, func(inner *Tx) error {
+			if _, err := inner.Repository().DeleteOneById(ctx, sqlc.Author{}, id); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("nested WithTx err = %v, want errRollback", err)
+		}
+		if _, err := bound.FindOneById(ctx, sqlc.Author{}, id, nil); err != nil {
+			t.Fatalf("FindOneById after savepoint rollback: %v", err)
+		}
+		if _, err := bound.DeleteSomeByIds(ctx, sqlc.Author{}, []int64{id}); err != nil {
+			return err
+		}
+		rows, err := bound.FindSomeByIds(ctx, sqlc.Author{}, []int64{id}, nil)
+		if err != nil || len(rows) != 0 {
+			t.Fatalf("FindSomeByIds in tx = %v, %v, want no rows", rows, err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx err = %v, want errRollback", err)
+	}
+
+	// 保留的 _mysql 函数委托给按驱动名创建的句柄
+	rows, err := FindAll_mysql(ctx, repo.DB(), sqlc.Author{})
+	if err != nil {
+		t.Fatalf("FindAll_mysql: %v", err)
+	}
+	if len(rows) != 1 || rows[0].GetId() != id {
+		t.Fatalf("rows = %+v, want the rolled back author", rows)
+	}
+}
+
+func TestRepositoryTables(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	authors := Model[sqlc.Author](repo)
+	books := Model[sqlc.Book](repo)
+	ids := seedAuthors(t, authors)
+	if _, err := books.CreateOne(ctx, sqlc.Book{Title: "chase", AuthorID: ids[0]}); err != nil {
+		t.Fatalf("CreateOne: %v", err)
+	}
+	// 同一连接上的另一个句柄只注册了books,关联表authors不可见
+	other := Model[sqlc.Book](NewRepository(repo.DB().DB, SQLite))
+
+	byAuthor := &QueryFilter{Conditions: []*QueryCondition{{Field: "author.name", Operator: "=", Value: "tom"}}}
+	if got, err := books.FindSomeByFilter(ctx, byAuthor, nil); err != nil || len(got) != 1 {
+		t.Fatalf("FindSomeByFilter = %+v, %v, want one book", got, err)
+	}
+	if _, err := other.FindSomeByFilter(ctx, byAuthor, nil); err == nil {
+		t.Fatal("FindSomeByFilter on another repository resolved an unregistered table")
+	}
+	if _, err := other.Repository().TableOf("authors"); err == nil {
+		t.Fatal("TableOf found a table registered on another repository")
+	}
+	// 绑定事务的句柄共享注册表
+	err := repo.WithTx(ctx, func(tx *Tx) error {
+		_, err := tx.Repository().TableOf("authors")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("TableOf in tx: %v", err)
+	}
+}
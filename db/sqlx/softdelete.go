@@ -0,0 +1,100 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrSoftDeleteUnsupported = errors.New("table does not support soft delete")
+
+// DeletedScope 软删除记录的查询范围
+type DeletedScope int
+
+const (
+	ExcludeDeleted DeletedScope = iota // 排除已删除记录(默认)
+	WithDeleted                        // 包含已删除记录
+	OnlyDeleted                        // 只查询已删除记录
+)
+
+// softDeleteColumn 获取表的软删除列,未声明时返回false
+// table可以是ITable或ITableUpdate
+func softDeleteColumn(table any) (string, bool) {
+	softDelete, ok := table.(ISoftDelete)
+	if !ok || softDelete.SoftDeleteColumn() == "" {
+		return "", false
+	}
+	return softDelete.SoftDeleteColumn(), true
+}
+
+// IsSoftDelete 判断表是否声明了软删除列
+func IsSoftDelete(table ITable) bool {
+	_, ok := softDeleteColumn(table)
+	return ok
+}
+
+// softDeleteClause 根据查询范围构建软删除条件,未声明软删除列的表返回空字符串
+func softDeleteClause(d Dialect, table any, scope DeletedScope) string {
+	column, ok := softDeleteColumn(table)
+	if !ok {
+		return ""
+	}
+	switch scope {
+	case WithDeleted:
+		return ""
+	case OnlyDeleted:
+		return d.Quote(column) + " IS NOT NULL"
+	default:
+		return d.Quote(column) + " IS NULL"
+	}
+}
+
+// RestoreSomeByIds 恢复已软删除的记录,返回影响的行数
+func (r *Repository) RestoreSomeByIds(ctx context.Context, table ITable, ids []int64) (int64, error) {
+	column, ok := softDeleteColumn(table)
+	if !ok {
+		return 0, ErrSoftDeleteUnsupported
+	}
+	if len(ids) == 0 {
+		return 0, errors.New("ids is empty")
+	}
+	d := r.dialect
+	query, args, err := sqlx.In(fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s IN (?) AND %s IS NOT NULL",
+		d.Quote(table.TableName()), d.Quote(column), d.Quote("id"), d.Quote(column)), ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build IN query: %w", err)
+	}
+	result, err := execContext(ctx, r.db, query, args...)
+	if err != nil {
+		log.Printf("failed to restore rows by ids, sql: %s, args: %v, error: %v", query, args, err)
+		return 0, fmt.Errorf("failed to restore rows by ids: %w", dbError(err))
+	}
+	return rowsAffected(result)
+}
+
+// PurgeDeleted 物理删除符合过滤条件的已软删除记录,返回影响的行数
+func (r *Repository) PurgeDeleted(ctx context.Context, table ITable, filter *QueryFilter) (int64, error) {
+	query, args, err := CreatePurgeSqlWithFilter(r.dialect, r.TableOf, table, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create purge query: %w", err)
+	}
+	result, err := execContext(ctx, r.db, query, args...)
+	if err != nil {
+		log.Printf("failed to purge rows, sql: %s, args: %v, error: %v", query, args, err)
+		return 0, fmt.Errorf("failed to purge rows: %w", dbError(err))
+	}
+	return rowsAffected(result)
+}
+
+// RestoreSomeByIds_mysql 见Repository.RestoreSomeByIds
+func RestoreSomeByIds_mysql(ctx context.Context, db DBTX, table ITable, ids []int64) (int64, error) {
+	return repositoryOf(db).RestoreSomeByIds(ctx, table, ids)
+}
+
+// PurgeDeleted_mysql 见Repository.PurgeDeleted
+func PurgeDeleted_mysql(ctx context.Context, db DBTX, table ITable, filter *QueryFilter) (int64, error) {
+	return repositoryOf(db).PurgeDeleted(ctx, table, filter)
+}
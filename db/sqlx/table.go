@@ -14,3 +14,8 @@ type ITableUpdate interface {
 	Columns() []string // 获取表的所有列名
 	GetId() int64      // 获取主键ID
 }
+
+// ISoftDelete 声明软删除列的表,删除时写入删除时间而不是物理删除
+type ISoftDelete interface {
+	SoftDeleteColumn() string // 获取软删除时间列名
+}
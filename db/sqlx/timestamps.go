@@ -0,0 +1,33 @@
+package sqlx
+
+import (
+	"reflect"
+	"time"
+)
+
+// 由通用层自动维护的审计时间列
+const (
+	CreatedAtColumn = "created_at" // 创建时间,插入时写入
+	UpdatedAtColumn = "updated_at" // 更新时间,插入和更新时写入
+)
+
+// hasColumn 判断列名列表中是否包含指定列
+func hasColumn(columns []string, column string) bool {
+	for _, col := range columns {
+		if col == column {
+			return true
+		}
+	}
+	return false
+}
+
+// insertTimestamp 插入时为未赋值的审计时间列填充当前时间
+func insertTimestamp(column string, field reflect.Value, now time.Time) (interface{}, bool) {
+	if column != CreatedAtColumn && column != UpdatedAtColumn {
+		return nil, false
+	}
+	if !field.IsZero() {
+		return nil, false
+	}
+	return now, true
+}
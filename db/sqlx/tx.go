@@ -0,0 +1,109 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DBTX sqlx.DB 与 sqlx.Tx 的公共接口,通用CRUD函数可在连接或事务上执行
+type DBTX interface {
+	sqlx.ExtContext
+}
+
+// Tx 事务句柄,通用CRUD与sqlc查询共享同一个 *sql.Tx
+type Tx struct {
+	tx    *sqlx.Tx    // sqlx事务
+	repo  *Repository // 绑定到该事务的数据库句柄
+	Sqlc  any         // 绑定到同一事务的sqlc查询实例,类型与Repository.Sqlc相同
+	depth int         // 保存点嵌套层数,0表示最外层事务
+}
+
+// WithTx 在新事务中执行fn,fn返回错误或发生panic时回滚,否则提交
+// 事务中的sqlc查询实例由连接方言默认的构造函数创建
+func WithTx(ctx context.Context, db *sqlx.DB, fn func(tx *Tx) error) error {
+	return withTx(ctx, repositoryOf(db), fn)
+}
+
+// withTx 在句柄的连接上开启新事务并执行fn,使用句柄的sqlc构造函数创建绑定到事务的sqlc查询实例
+func withTx(ctx context.Context, r *Repository, fn func(tx *Tx) error) (err error) {
+	if r.conn == nil {
+		return fmt.Errorf("no database connection to begin transaction")
+	}
+	sqlxTx, err := r.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	tx := &Tx{
+		tx:   sqlxTx,
+		Sqlc: r.newSqlc(sqlxTx.Tx),
+	}
+	tx.repo = r.bind(tx)
+	defer func() {
+		if p := recover(); p != nil {
+			if rbErr := sqlxTx.Rollback(); rbErr != nil {
+				log.Printf("failed to rollback transaction after panic, error: %v", rbErr)
+			}
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		if rbErr := sqlxTx.Rollback(); rbErr != nil {
+			log.Printf("failed to rollback transaction, error: %v", rbErr)
+		}
+		return err
+	}
+	if err := sqlxTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", dbError(err))
+	}
+	return nil
+}
+
+// WithTx 在当前事务中通过保存点执行fn,失败时只回滚到保存点,不影响外层事务
+func (t *Tx) WithTx(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	nested := &Tx{
+		tx:    t.tx,
+		Sqlc:  t.Sqlc,
+		depth: t.depth + 1,
+	}
+	nested.repo = t.repo.bind(nested)
+	savepoint := fmt.Sprintf("sp_%d", nested.depth)
+	if _, err := t.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			if _, rbErr := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+				log.Printf("failed to rollback to savepoint after panic, savepoint: %s, error: %v", savepoint, rbErr)
+			}
+			panic(p)
+		}
+	}()
+	if err := fn(nested); err != nil {
+		if _, rbErr := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			log.Printf("failed to rollback to savepoint, savepoint: %s, error: %v", savepoint, rbErr)
+		}
+		return err
+	}
+	if _, err := t.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// Sqlx 返回底层的sqlx事务
+func (t *Tx) Sqlx() *sqlx.Tx {
+	return t.tx
+}
+
+// Repository 返回绑定到该事务的数据库句柄
+func (t *Tx) Repository() *Repository {
+	return t.repo
+}
+
+// TxTable 创建绑定到事务的数据库操作模型
+func TxTable[T ITable](tx *Tx) *Table[T] {
+	return Model[T](tx.repo)
+}
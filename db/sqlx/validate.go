@@ -0,0 +1,373 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidateTag 声明校验规则的结构体标签,例如 `validate:"required,max=64,regex=^[a-z]+$"`
+// 规则以逗号分隔,regex规则会读取标签剩余的全部内容,因此必须放在最后
+// 支持的规则: required, min=N, max=N(字符串为字符数,数字为取值), maxbytes=N(字符串的字节数),
+// regex=R, oneof=a b c, fk=table.column
+//
+// required只拒绝nil指针、无效的sql.NullXxx和空字符串,0和false满足required:
+// 非指针的数字和布尔字段无法区分未提供和零值,而0和false常常是合法的取值(例如库存为0、未启用)
+// 不允许为0的字段应使用min=1,外键字段使用fk,0不对应任何记录
+const ValidateTag = "validate"
+
+// IValidationRules 按列名提供校验规则的表,由生成器根据建表语句生成
+// 与字段上的validate标签合并,同名规则以标签为准
+type IValidationRules interface {
+	ValidationRules() map[string]string
+}
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // 字段名,取自json标签
+	Rule    string `json:"rule"`    // 未通过的规则
+	Message string `json:"message"` // 错误描述
+}
+
+// ValidationErrors 逐字段的校验错误列表
+type ValidationErrors []FieldError
+
+// Error 实现 error 接口
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validationRule 解析后的单条校验规则
+type validationRule struct {
+	name    string
+	param   string
+	pattern *regexp.Regexp // regex规则编译后的正则表达式
+}
+
+// fieldRules 结构体单个字段生效的校验规则
+type fieldRules struct {
+	index int    // 字段下标
+	name  string // 校验错误中使用的字段名
+	rules []validationRule
+}
+
+// rulesCache 按结构体类型缓存解析后的校验规则,避免每次校验重复解析规则和编译正则
+var rulesCache sync.Map // reflect.Type -> []fieldRules
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseRules 解析规则字符串
+func parseRules(value string) ([]validationRule, error) {
+	var rules []validationRule
+	for value != "" {
+		var item string
+		if strings.HasPrefix(value, "regex=") {
+			item, value = value, ""
+		} else if idx := strings.Index(value, ","); idx >= 0 {
+			item, value = value[:idx], value[idx+1:]
+		} else {
+			item, value = value, ""
+		}
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, param, _ := strings.Cut(item, "=")
+		rule := validationRule{name: name, param: param}
+		switch name {
+		case "required":
+		case "min", "max", "maxbytes":
+			if _, err := strconv.ParseFloat(param, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s rule: %s", name, item)
+			}
+		case "regex":
+			pattern, err := regexp.Compile(param)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex rule: %w", err)
+			}
+			rule.pattern = pattern
+		case "oneof":
+			if strings.TrimSpace(param) == "" {
+				return nil, fmt.Errorf("invalid oneof rule: %s", item)
+			}
+		case "fk":
+			table, column, ok := strings.Cut(param, ".")
+			if !ok || !identifierRegexp.MatchString(table) || !identifierRegexp.MatchString(column) {
+				return nil, fmt.Errorf("invalid fk rule: %s", item)
+			}
+		default:
+			return nil, fmt.Errorf("unknown validation rule: %s", name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// mergeRules 合并生成的规则与标签规则,同名规则以标签为准
+func mergeRules(generated, tagged []validationRule) []validationRule {
+	if len(generated) == 0 {
+		return tagged
+	}
+	merged := make([]validationRule, 0, len(generated)+len(tagged))
+	for _, rule := range generated {
+		overridden := false
+		for _, tagRule := range tagged {
+			if tagRule.name == rule.name {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, rule)
+		}
+	}
+	return append(merged, tagged...)
+}
+
+// validateStruct 按校验规则校验记录,partial为true时只校验非nil的指针字段(用于XxxUpdate)
+// 返回的error为ValidationErrors时表示校验未通过,其他错误为规则或数据库错误
+func validateStruct(ctx context.Context, r *Repository, item any, partial bool) error {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot validate %T", item)
+	}
+	fields, err := typeRules(v.Type(), item)
+	if err != nil {
+		return err
+	}
+	var fieldErrs ValidationErrors
+	for _, f := range fields {
+		field := v.Field(f.index)
+		if partial && field.Kind() == reflect.Pointer && field.IsNil() {
+			continue
+		}
+		fieldErr, err := checkField(ctx, r, f.name, fieldValue(field), f.rules)
+		if err != nil {
+			return err
+		}
+		if fieldErr != nil {
+			fieldErrs = append(fieldErrs, *fieldErr)
+		}
+	}
+	if len(fieldErrs) > 0 {
+		return fieldErrs
+	}
+	return nil
+}
+
+// typeRules 返回结构体类型各字段生效的校验规则,没有规则的字段不出现在结果中
+// 生成的规则由类型的ValidationRules方法提供,对同一类型不变,因此按类型缓存
+func typeRules(t reflect.Type, item any) ([]fieldRules, error) {
+	if cached, ok := rulesCache.Load(t); ok {
+		return cached.([]fieldRules), nil
+	}
+	var generated map[string]string
+	if provider, ok := item.(IValidationRules); ok {
+		generated = provider.ValidationRules()
+	}
+	var fields []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		rules, err := structFieldRules(structField, generated)
+		if err != nil {
+			return nil, err
+		}
+		if len(rules) > 0 {
+			fields = append(fields, fieldRules{index: i, name: fieldName(structField), rules: rules})
+		}
+	}
+	rulesCache.Store(t, fields)
+	return fields, nil
+}
+
+// structFieldRules 返回字段生效的校验规则,生成的列规则与validate标签合并
+func structFieldRules(structField reflect.StructField, generated map[string]string) ([]validationRule, error) {
+	column := strings.Split(structField.Tag.Get("db"), ",")[0]
+	tagRules, err := parseRules(structField.Tag.Get(ValidateTag))
+	if err != nil {
+		return nil, err
+	}
+	var columnRules []validationRule
+	if column != "" && column != "-" {
+		if columnRules, err = parseRules(generated[column]); err != nil {
+			return nil, err
+		}
+	}
+	return mergeRules(columnRules, tagRules), nil
+}
+
+// fieldName 返回校验错误中使用的字段名,依次取json标签、db标签和字段名
+func fieldName(structField reflect.StructField) string {
+	name := strings.Split(structField.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		name = strings.Split(structField.Tag.Get("db"), ",")[0]
+	}
+	if name == "" || name == "-" {
+		name = structField.Name
+	}
+	return name
+}
+
+// FieldRules 返回结构体各字段生效的校验规则,键为字段名(同校验错误中的Field),
+// 值为 规则名 -> 参数,没有规则的字段不出现在结果中,可用于生成接口文档
+func FieldRules(item any) (map[string]map[string]string, error) {
+	t := reflect.TypeOf(item)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot get rules of %T", item)
+	}
+	fields, err := typeRules(t, item)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]string, len(fields))
+	for _, f := range fields {
+		params := make(map[string]string, len(f.rules))
+		for _, rule := range f.rules {
+			params[rule.name] = rule.param
+		}
+		result[f.name] = params
+	}
+	return result, nil
+}
+
+// fieldValue 取出字段的实际值,nil指针和无效的sql.NullXxx返回nil
+func fieldValue(field reflect.Value) interface{} {
+	for field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	value := field.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil
+		}
+		return v
+	}
+	return value
+}
+
+// checkField 按顺序校验字段,返回第一个未通过的规则
+func checkField(ctx context.Context, r *Repository, name string, value interface{}, rules []validationRule) (*FieldError, error) {
+	fail := func(rule validationRule, format string, args ...interface{}) (*FieldError, error) {
+		return &FieldError{Field: name, Rule: rule.name, Message: fmt.Sprintf(format, args...)}, nil
+	}
+	// 只有nil指针和无效的sql.NullXxx视为未提供,只校验required规则
+	// 0和false是合法的值,其余规则照常校验,原因见ValidateTag
+	if value == nil {
+		for _, rule := range rules {
+			if rule.name == "required" {
+				return fail(rule, "不能为空")
+			}
+		}
+		return nil, nil
+	}
+	for _, rule := range rules {
+		switch rule.name {
+		case "required":
+			// 必填的字符串不能为空字符串
+			if s, ok := value.(string); ok && s == "" {
+				return fail(rule, "不能为空")
+			}
+		case "maxbytes":
+			// MySQL的TEXT等类型按字节限制长度
+			limit, _ := strconv.Atoi(rule.param)
+			if s, ok := value.(string); ok && len(s) > limit {
+				return fail(rule, "长度不能大于%s字节", rule.param)
+			}
+		case "min", "max":
+			limit, _ := strconv.ParseFloat(rule.param, 64)
+			if s, ok := value.(string); ok {
+				length := float64(utf8.RuneCountInString(s))
+				if rule.name == "min" && length < limit {
+					return fail(rule, "长度不能小于%s", rule.param)
+				}
+				if rule.name == "max" && length > limit {
+					return fail(rule, "长度不能大于%s", rule.param)
+				}
+				continue
+			}
+			number, ok := numberValue(value)
+			if !ok {
+				continue
+			}
+			if rule.name == "min" && number < limit {
+				return fail(rule, "不能小于%s", rule.param)
+			}
+			if rule.name == "max" && number > limit {
+				return fail(rule, "不能大于%s", rule.param)
+			}
+		case "regex":
+			s, ok := value.(string)
+			if ok && !rule.pattern.MatchString(s) {
+				return fail(rule, "格式不正确")
+			}
+		case "oneof":
+			options := strings.Fields(rule.param)
+			actual := fmt.Sprint(value)
+			found := false
+			for _, option := range options {
+				if option == actual {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fail(rule, "必须是以下值之一: %s", strings.Join(options, ", "))
+			}
+		case "fk":
+			table, column, _ := strings.Cut(rule.param, ".")
+			exists, err := r.recordExists(ctx, table, column, value)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				return fail(rule, "引用的%s记录不存在", table)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// numberValue 将整数和浮点数转换为float64
+func numberValue(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch {
+	case v.CanInt():
+		return float64(v.Int()), true
+	case v.CanUint():
+		return float64(v.Uint()), true
+	case v.CanFloat():
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// recordExists 查询被引用的记录是否存在
+func (r *Repository) recordExists(ctx context.Context, table, column string, value interface{}) (bool, error) {
+	d := r.dialect
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", d.Quote(table), d.Quote(column))
+	var count int64
+	if err := r.db.QueryRowxContext(ctx, r.db.Rebind(query), value).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check foreign key %s.%s: %w", table, column, dbError(err))
+	}
+	return count > 0, nil
+}
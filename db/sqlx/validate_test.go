@@ -0,0 +1,178 @@
+package sqlx
+
+import (
+	"context"
+	sqlc "crud/db/sqlc"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validatedItem 覆盖各类校验规则的测试结构
+type validatedItem struct {
+	Count   int             `json:"count" validate:"required,min=0,max=10"`
+	Rank    int             `json:"rank" validate:"min=1"`
+	Enabled bool            `json:"enabled" validate:"required"`
+	Name    string          `json:"name" validate:"required,max=4"`
+	Body    string          `json:"body" validate:"maxbytes=6"`
+	Code    string          `json:"code" validate:"regex=^[a-z]+$"`
+	Status  string          `json:"status" validate:"oneof=draft published"`
+	Note    sql.NullString  `json:"note" validate:"required"`
+	Score   sql.NullFloat64 `json:"score" validate:"max=5"`
+}
+
+// validItem 返回通过全部规则的记录
+func validItem() validatedItem {
+	return validatedItem{
+		Count:  1,
+		Rank:   1,
+		Name:   "tom",
+		Body:   "hello",
+		Code:   "abc",
+		Status: "draft",
+		Note:   sql.NullString{String: "n", Valid: true},
+	}
+}
+
+func TestValidateStruct(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(item *validatedItem)
+		want   []string // 未通过的 字段:规则
+	}{
+		{"valid", func(item *validatedItem) {}, nil},
+		// 0和false是合法的值,required只拒绝未提供的值
+		{"zero int and false bool satisfy required", func(item *validatedItem) { item.Count, item.Enabled = 0, false }, nil},
+		{"min runs on zero values", func(item *validatedItem) { item.Rank = 0 }, []string{"rank:min"}},
+		{"max", func(item *validatedItem) { item.Count = 11 }, []string{"count:max"}},
+		{"empty required string", func(item *validatedItem) { item.Name = "" }, []string{"name:required"}},
+		{"max counts characters", func(item *validatedItem) { item.Name = "汤姆猫" }, nil},
+		{"max rejects long strings", func(item *validatedItem) { item.Name = "jerry" }, []string{"name:max"}},
+		{"maxbytes counts bytes", func(item *validatedItem) { item.Body = "汤姆猫" }, []string{"body:maxbytes"}},
+		{"maxbytes accepts the limit", func(item *validatedItem) { item.Body = "汤姆" }, nil},
+		{"regex", func(item *validatedItem) { item.Code = "ABC" }, []string{"code:regex"}},
+		{"regex runs on empty strings", func(item *validatedItem) { item.Code = "" }, []string{"code:regex"}},
+		{"oneof", func(item *validatedItem) { item.Status = "deleted" }, []string{"status:oneof"}},
+		{"invalid null is missing", func(item *validatedItem) { item.Note = sql.NullString{} }, []string{"note:required"}},
+		{"valid null with empty string", func(item *validatedItem) { item.Note = sql.NullString{Valid: true} }, []string{"note:required"}},
+		{"valid null with zero number", func(item *validatedItem) { item.Score = sql.NullFloat64{Valid: true} }, nil},
+		{"null number", func(item *validatedItem) { item.Score = sql.NullFloat64{Float64: 6, Valid: true} }, []string{"score:max"}},
+		{"all errors are reported", func(item *validatedItem) { item.Rank, item.Code = 0, "!" }, []string{"rank:min", "code:regex"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := validItem()
+			tt.modify(&item)
+			err := validateStruct(context.Background(), nil, item, false)
+			var got []string
+			var fieldErrs ValidationErrors
+			if errors.As(err, &fieldErrs) {
+				for _, fieldErr := range fieldErrs {
+					got = append(got, fieldErr.Field+":"+fieldErr.Rule)
+				}
+			} else if err != nil {
+				t.Fatalf("validateStruct: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("failed rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStructPartial(t *testing.T) {
+	type itemUpdate struct {
+		Count *int    `json:"count" validate:"required,min=1"`
+		Name  *string `json:"name" validate:"required,max=4"`
+	}
+	zero, long := 0, "jerry"
+	tests := []struct {
+		name   string
+		update itemUpdate
+		want   string
+	}{
+		{"missing fields are skipped", itemUpdate{}, ""},
+		{"provided zero value is validated", itemUpdate{Count: &zero}, "count: 不能小于1"},
+		{"provided string is validated", itemUpdate{Name: &long}, "name: 长度不能大于4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStruct(context.Background(), nil, tt.update, true)
+			if got := errorText(err); got != tt.want {
+				t.Fatalf("err = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		rules   string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"required, max=4 ,oneof=a b", []string{"required", "max", "oneof"}, false},
+		{"regex=^[a-z]+$", []string{"regex"}, false},
+		{"regex=[", nil, true},
+		{"min=x", nil, true},
+		{"maxbytes=", nil, true},
+		{"unknown", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rules, func(t *testing.T) {
+			rules, err := parseRules(tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, rule := range rules {
+				names = append(names, rule.name)
+				// 正则在解析时编译一次
+				if rule.name == "regex" && rule.pattern == nil {
+					t.Fatal("regex rule is not compiled")
+				}
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Fatalf("rules = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateForeignKey(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+	authors := Model[sqlc.Author](repo)
+	books := Model[sqlc.Book](repo)
+	ids := seedAuthors(t, authors)
+
+	tests := []struct {
+		name string
+		book sqlc.Book
+		want string
+	}{
+		{"existing author", sqlc.Book{Title: "t", AuthorID: ids[0]}, ""},
+		// 0不再被当作未提供,按外键校验
+		{"zero author id", sqlc.Book{Title: "t"}, "author_id: 引用的authors记录不存在"},
+		{"missing author", sqlc.Book{Title: "t", AuthorID: 100}, "author_id: 引用的authors记录不存在"},
+		{"missing title", sqlc.Book{AuthorID: ids[0]}, "title: 不能为空"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorText(books.Validate(ctx, tt.book)); got != tt.want {
+				t.Fatalf("err = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// errorText 返回错误信息,err为nil时返回空字符串
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return strings.TrimSpace(err.Error())
+}
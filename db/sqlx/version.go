@@ -0,0 +1,106 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// VersionColumn 乐观锁版本号列名,表中存在该列时按版本号更新
+const VersionColumn = "version"
+
+var (
+	ErrVersionRequired = errors.New("version is required for optimistic locking")
+	ErrVersionConflict = errors.New("record has been modified by others")
+)
+
+// HasVersion 判断表是否包含乐观锁版本号列
+func HasVersion(table any) bool {
+	switch t := table.(type) {
+	case ITable:
+		return hasColumn(t.Columns(), VersionColumn)
+	case ITableUpdate:
+		return hasColumn(t.Columns(), VersionColumn)
+	}
+	return false
+}
+
+// VersionOf 读取记录中的版本号,字段未设置时返回false
+// table可以是ITable或ITableUpdate,版本号字段可以是整数或整数指针
+func VersionOf(table any) (int64, bool) {
+	field, ok := versionField(reflect.Indirect(reflect.ValueOf(table)))
+	if !ok {
+		return 0, false
+	}
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return 0, false
+		}
+		field = field.Elem()
+	}
+	if !field.CanInt() {
+		return 0, false
+	}
+	return field.Int(), true
+}
+
+// SetVersion 设置记录中的版本号,table必须为指针,不包含版本号字段时返回false
+func SetVersion(table any, version int64) bool {
+	v := reflect.ValueOf(table)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return false
+	}
+	field, ok := versionField(v.Elem())
+	if !ok || !field.CanSet() {
+		return false
+	}
+	if field.Kind() == reflect.Pointer {
+		value := reflect.New(field.Type().Elem())
+		if !value.Elem().CanInt() {
+			return false
+		}
+		value.Elem().SetInt(version)
+		field.Set(value)
+		return true
+	}
+	if !field.CanInt() {
+		return false
+	}
+	field.SetInt(version)
+	return true
+}
+
+// versionIncrement 构建版本号自增的赋值表达式
+// 右侧的列名带表名限定,PostgreSQL的ON CONFLICT DO UPDATE中未限定的列名与excluded存在歧义
+func versionIncrement(d Dialect, tableName string) string {
+	column := d.Quote(VersionColumn)
+	return column + " = " + d.Quote(tableName) + "." + column + " + 1"
+}
+
+// versionField 按db标签查找版本号字段
+// 不使用columnMapper.FieldByName,它在字段不存在时返回结构体本身,并会为nil指针字段分配内存
+func versionField(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	fi, ok := columnMapper.TypeMap(v.Type()).Names[VersionColumn]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return v.FieldByIndex(fi.Index), true
+}
+
+// versionConflict 区分按版本号更新0行的原因,记录仍存在时返回ErrVersionConflict
+func (r *Repository) versionConflict(ctx context.Context, table ITableUpdate) error {
+	d := r.dialect
+	query := fmt.Sprintf("%s WHERE %s = ?%s", buildBaseCount(d, table.TableName()), d.Quote("id"), andSoftDeleteClause(d, table))
+	var count int64
+	if err := r.db.QueryRowxContext(ctx, r.db.Rebind(query), table.GetId()).Scan(&count); err != nil {
+		return fmt.Errorf("failed to check version conflict: %w", dbError(err))
+	}
+	if count > 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
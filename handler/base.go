@@ -3,7 +3,11 @@ package handler
 import (
 	"crud/db/sqlx"
 	"crud/pkg/response"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -15,12 +19,17 @@ type BaseHandler[T any, U any] interface {
 	GetByFilter(c echo.Context) error
 	GetAll(c echo.Context) error
 	Create(c echo.Context) error
+	CreateBatch(c echo.Context) error
+	Upsert(c echo.Context) error
 	DeleteById(c echo.Context) error
 	DeleteByIds(c echo.Context) error
 	DeleteByFilter(c echo.Context) error
 	UpdateById(c echo.Context) error
 	UpdateByIds(c echo.Context) error
 	UpdateByFilter(c echo.Context) error
+	GetDeleted(c echo.Context) error
+	RestoreById(c echo.Context) error
+	PurgeDeleted(c echo.Context) error
 }
 
 // BaseCrudHandler 基础CRUD处理器实现
@@ -29,16 +38,22 @@ type BaseCrudHandler[T sqlx.ITable, U sqlx.ITableUpdate] struct {
 	crud         *sqlx.Table[T]
 }
 
-// NewBaseCrudHandler 创建新的基础CRUD处理器
+// NewBaseCrudHandler 创建新的基础CRUD处理器,写入前在Before钩子之后校验请求内容
 func NewBaseCrudHandler[T sqlx.ITable, U sqlx.ITableUpdate](resourceName string, crud *sqlx.Table[T]) *BaseCrudHandler[T, U] {
 	return &BaseCrudHandler[T, U]{
 		resourceName: resourceName,
-		crud:         crud,
+		crud:         crud.WithValidation(),
 	}
 }
 
 // GetById 根据ID获取单个资源
 func (h *BaseCrudHandler[T, U]) GetById(c echo.Context) error {
+	return h.GetByIdWithInclude(c, sqlx.ParseIncludeFromQuery(c.QueryParams()))
+}
+
+// GetByIdWithInclude 根据ID获取单个资源并加载include中的关联,忽略请求中的include参数
+// 用于固定加载某些关联的自定义路由
+func (h *BaseCrudHandler[T, U]) GetByIdWithInclude(c echo.Context, include []string) error {
 	var singleId SingleId
 	if err := c.Bind(&singleId); err != nil {
 		return response.BadRequest(err)
@@ -46,11 +61,27 @@ func (h *BaseCrudHandler[T, U]) GetById(c echo.Context) error {
 	if singleId.Id == 0 {
 		return response.BadRequest(fmt.Errorf("%s ID不能为空", h.resourceName))
 	}
-	item, err := h.crud.FindOneById(c.Request().Context(), singleId.Id)
+	fieldFilter, columns, err := h.parseFieldFilter(c)
 	if err != nil {
-		return response.DatabaseError(err)
+		return response.BadRequest(err)
+	}
+	fieldFilter, err = h.includeColumns(include, fieldFilter)
+	if err != nil {
+		return err
+	}
+	item, err := h.crud.FindOneById(c.Request().Context(), singleId.Id, fieldFilter)
+	if err != nil {
+		return h.findError(err)
 	}
-	return response.Success(c, item)
+	setETag(c, item)
+	if len(include) > 0 {
+		result, err := h.withRelations(c, []T{item}, columns, include)
+		if err != nil {
+			return response.DatabaseError(err)
+		}
+		return response.Success(c, result[0])
+	}
+	return response.Success(c, pickItem(item, columns))
 }
 
 // GetByIds 根据ID列表获取多个资源
@@ -62,11 +93,26 @@ func (h *BaseCrudHandler[T, U]) GetByIds(c echo.Context) error {
 	if len(groupIds.Ids) == 0 {
 		return response.BadRequest(fmt.Errorf("%s ID列表不能为空", h.resourceName))
 	}
-	items, err := h.crud.FindSomeByIds(c.Request().Context(), groupIds.Ids)
+	fieldFilter, columns, err := h.parseFieldFilter(c)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+	include, fieldFilter, err := h.parseInclude(c, fieldFilter)
+	if err != nil {
+		return err
+	}
+	items, err := h.crud.FindSomeByIds(c.Request().Context(), groupIds.Ids, fieldFilter)
 	if err != nil {
 		return response.DatabaseError(err)
 	}
-	return response.Success(c, items)
+	if len(include) > 0 {
+		result, err := h.withRelations(c, items, columns, include)
+		if err != nil {
+			return response.DatabaseError(err)
+		}
+		return response.Success(c, result)
+	}
+	return response.Success(c, pickItems(items, columns))
 }
 
 // GetByFilter 根据过滤条件获取资源
@@ -76,11 +122,53 @@ func (h *BaseCrudHandler[T, U]) GetByFilter(c echo.Context) error {
 	if err != nil {
 		return response.BadRequest(err)
 	}
-	items, err := h.crud.FindSomeByFilter(c.Request().Context(), filter)
+	return h.listByFilter(c, filter)
+}
+
+// listByFilter 按过滤条件返回资源列表,包含分页参数时返回分页结果
+func (h *BaseCrudHandler[T, U]) listByFilter(c echo.Context, filter *sqlx.QueryFilter) error {
+	fieldFilter, columns, err := h.parseFieldFilter(c)
 	if err != nil {
-		return response.DatabaseError(err)
+		return response.BadRequest(err)
 	}
-	return response.Success(c, items)
+	include, fieldFilter, err := h.parseInclude(c, fieldFilter)
+	if err != nil {
+		return err
+	}
+	if filter.IsPaged() {
+		page, err := h.crud.FindPageByFilter(c.Request().Context(), filter, fieldFilter)
+		if err != nil {
+			return queryError(err)
+		}
+		setPageLinks(c, page.Page, page.PageSize, page.HasNext, page.NextCursor)
+		if len(include) > 0 {
+			items, err := h.withRelations(c, page.Items, columns, include)
+			if err != nil {
+				return response.DatabaseError(err)
+			}
+			return response.Success(c, &sqlx.PageResult[map[string]interface{}]{
+				Items:      items,
+				Total:      page.Total,
+				Page:       page.Page,
+				PageSize:   page.PageSize,
+				HasNext:    page.HasNext,
+				NextCursor: page.NextCursor,
+			})
+		}
+		return response.Success(c, pickPage(page, columns))
+	}
+	items, err := h.crud.FindSomeByFilter(c.Request().Context(), filter, fieldFilter)
+	if err != nil {
+		return queryError(err)
+	}
+	if len(include) > 0 {
+		result, err := h.withRelations(c, items, columns, include)
+		if err != nil {
+			return response.DatabaseError(err)
+		}
+		return response.Success(c, result)
+	}
+	return response.Success(c, pickItems(items, columns))
 }
 
 // GetAll 获取所有资源
@@ -94,11 +182,44 @@ func (h *BaseCrudHandler[T, U]) Create(c echo.Context) error {
 	if err := c.Bind(&item); err != nil {
 		return response.BadRequest(err)
 	}
-	err := h.crud.CreateOne(c.Request().Context(), item)
+	created, err := h.crud.CreateOne(c.Request().Context(), item)
 	if err != nil {
-		return response.DatabaseError(err)
+		return queryError(err)
 	}
-	return response.Success(c, item)
+	return response.Success(c, created)
+}
+
+// CreateBatch 批量创建资源
+func (h *BaseCrudHandler[T, U]) CreateBatch(c echo.Context) error {
+	var items []T
+	if err := c.Bind(&items); err != nil {
+		return response.BadRequest(err)
+	}
+	if len(items) == 0 {
+		return response.BadRequest(fmt.Errorf("%s列表不能为空", h.resourceName))
+	}
+	ids, err := h.crud.CreateMany(c.Request().Context(), items)
+	if err != nil {
+		return queryError(err)
+	}
+	return response.Success(c, GroupIds{Ids: ids})
+}
+
+// Upsert 批量插入或更新资源,冲突时更新update_columns参数指定的列
+func (h *BaseCrudHandler[T, U]) Upsert(c echo.Context) error {
+	var items []T
+	if err := c.Bind(&items); err != nil {
+		return response.BadRequest(err)
+	}
+	if len(items) == 0 {
+		return response.BadRequest(fmt.Errorf("%s列表不能为空", h.resourceName))
+	}
+	updateColumns := sqlx.SplitFieldList(c.QueryParam(UpdateColumnsKey))
+	affected, err := h.crud.UpsertMany(c.Request().Context(), items, updateColumns)
+	if err != nil {
+		return queryError(err)
+	}
+	return response.Success(c, AffectedResult{Affected: affected})
 }
 
 // DeleteById 删除单个资源
@@ -110,11 +231,14 @@ func (h *BaseCrudHandler[T, U]) DeleteById(c echo.Context) error {
 	if singleId.Id == 0 {
 		return response.BadRequest(fmt.Errorf("%s ID不能为空", h.resourceName))
 	}
-	err := h.crud.DeleteOne(c.Request().Context(), singleId.Id)
+	affected, err := h.crud.DeleteOne(c.Request().Context(), singleId.Id)
 	if err != nil {
-		return response.DatabaseError(err)
+		return h.findError(err)
+	}
+	if affected == 0 {
+		return h.findError(sqlx.ErrNotFound)
 	}
-	return response.Success(c, nil)
+	return response.Success(c, AffectedResult{Affected: affected})
 }
 
 // DeleteByIds 批量删除资源
@@ -126,11 +250,11 @@ func (h *BaseCrudHandler[T, U]) DeleteByIds(c echo.Context) error {
 	if len(groupIds.Ids) == 0 {
 		return response.BadRequest(fmt.Errorf("%s ID列表不能为空", h.resourceName))
 	}
-	err := h.crud.DeleteSomeByIds(c.Request().Context(), groupIds.Ids)
+	affected, err := h.crud.DeleteSomeByIds(c.Request().Context(), groupIds.Ids)
 	if err != nil {
 		return response.DatabaseError(err)
 	}
-	return response.Success(c, nil)
+	return response.Success(c, AffectedResult{Affected: affected})
 }
 
 // DeleteByFilter 根据过滤条件删除资源
@@ -140,14 +264,17 @@ func (h *BaseCrudHandler[T, U]) DeleteByFilter(c echo.Context) error {
 	if err != nil {
 		return response.BadRequest(err)
 	}
-	err = h.crud.DeleteSomeByFilter(c.Request().Context(), filter)
+	if err := h.requireConditions(filter); err != nil {
+		return err
+	}
+	affected, err := h.crud.DeleteSomeByFilter(c.Request().Context(), filter)
 	if err != nil {
-		return response.DatabaseError(err)
+		return queryError(err)
 	}
-	return response.Success(c, nil)
+	return response.Success(c, AffectedResult{Affected: affected})
 }
 
-// UpdateById 更新单个资源
+// UpdateById 更新单个资源,包含版本号的资源可通过If-Match请求头传递当前版本号
 func (h *BaseCrudHandler[T, U]) UpdateById(c echo.Context) error {
 	var item U
 	if err := c.Bind(&item); err != nil {
@@ -156,17 +283,35 @@ func (h *BaseCrudHandler[T, U]) UpdateById(c echo.Context) error {
 	if item.GetId() == 0 {
 		return response.BadRequest(fmt.Errorf("%s ID不能为空", h.resourceName))
 	}
-	err := h.crud.UpdateOne(c.Request().Context(), item)
+	if err := applyIfMatch(c, &item); err != nil {
+		return response.BadRequest(err)
+	}
+	affected, err := h.crud.UpdateOne(c.Request().Context(), item)
 	if err != nil {
-		return response.DatabaseError(err)
+		if errors.Is(err, sqlx.ErrVersionConflict) {
+			return response.ConflictError(fmt.Errorf("%s已被修改,请刷新后重试", h.resourceName))
+		}
+		if errors.Is(err, sqlx.ErrVersionRequired) {
+			return response.BadRequest(fmt.Errorf("更新%s需要通过If-Match请求头或version字段提供版本号", h.resourceName))
+		}
+		return h.findError(err)
 	}
-	return response.Success(c, item)
+	if affected == 0 {
+		return h.findError(sqlx.ErrNotFound)
+	}
+	updated, err := h.crud.FindOneById(c.Request().Context(), item.GetId(), nil)
+	if err != nil {
+		return h.findError(err)
+	}
+	setETag(c, updated)
+	return response.Success(c, updated)
 }
 
-// UpdateByIds 批量更新资源
+// UpdateByIds 批量更新资源,ID列表通过查询参数ids传递,请求体为更新内容
 func (h *BaseCrudHandler[T, U]) UpdateByIds(c echo.Context) error {
+	// 请求体只能读取一次,ID列表从查询参数绑定
 	var groupIds GroupIds
-	if err := c.Bind(&groupIds); err != nil {
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &groupIds); err != nil {
 		return response.BadRequest(err)
 	}
 	if len(groupIds.Ids) == 0 {
@@ -176,11 +321,11 @@ func (h *BaseCrudHandler[T, U]) UpdateByIds(c echo.Context) error {
 	if err := c.Bind(&item); err != nil {
 		return response.BadRequest(err)
 	}
-	err := h.crud.UpdateSomeByIds(c.Request().Context(), item, groupIds.Ids)
+	affected, err := h.crud.UpdateSomeByIds(c.Request().Context(), item, groupIds.Ids)
 	if err != nil {
-		return response.DatabaseError(err)
+		return queryError(err)
 	}
-	return response.Success(c, item)
+	return response.Success(c, AffectedResult{Affected: affected})
 }
 
 // UpdateByFilter 根据过滤条件更新资源
@@ -190,15 +335,26 @@ func (h *BaseCrudHandler[T, U]) UpdateByFilter(c echo.Context) error {
 	if err != nil {
 		return response.BadRequest(err)
 	}
+	if err := h.requireConditions(filter); err != nil {
+		return err
+	}
 	var item U
 	if err := c.Bind(&item); err != nil {
 		return response.BadRequest(err)
 	}
-	err = h.crud.UpdateSomeByFilter(c.Request().Context(), item, filter)
+	affected, err := h.crud.UpdateSomeByFilter(c.Request().Context(), item, filter)
 	if err != nil {
-		return response.DatabaseError(err)
+		return queryError(err)
 	}
-	return response.Success(c, item)
+	return response.Success(c, AffectedResult{Affected: affected})
+}
+
+// 在查询参数中标记upsert冲突时需要更新的列
+const UpdateColumnsKey = "update_columns"
+
+// AffectedResult 批量写操作的返回结果
+type AffectedResult struct {
+	Affected int64 `json:"affected"` // 影响的行数
 }
 
 type GroupIds struct {
@@ -208,3 +364,280 @@ type GroupIds struct {
 type SingleId struct {
 	Id int64 `json:"id" param:"id" query:"id" form:"id"`
 }
+
+// GetDeleted 获取回收站中已软删除的资源
+func (h *BaseCrudHandler[T, U]) GetDeleted(c echo.Context) error {
+	if err := h.checkSoftDelete(); err != nil {
+		return err
+	}
+	filter, err := sqlx.ParseQueryFilterFromUrlParams(c.QueryParams())
+	if err != nil {
+		return response.BadRequest(err)
+	}
+	if filter == nil {
+		filter = &sqlx.QueryFilter{}
+	}
+	filter.Deleted = sqlx.OnlyDeleted
+	return h.listByFilter(c, filter)
+}
+
+// RestoreById 恢复已软删除的单个资源
+func (h *BaseCrudHandler[T, U]) RestoreById(c echo.Context) error {
+	if err := h.checkSoftDelete(); err != nil {
+		return err
+	}
+	var singleId SingleId
+	if err := c.Bind(&singleId); err != nil {
+		return response.BadRequest(err)
+	}
+	if singleId.Id == 0 {
+		return response.BadRequest(fmt.Errorf("%s ID不能为空", h.resourceName))
+	}
+	affected, err := h.crud.Restore(c.Request().Context(), []int64{singleId.Id})
+	if err != nil {
+		return response.DatabaseError(err)
+	}
+	if affected == 0 {
+		return response.NotFound(fmt.Errorf("回收站中不存在该%s", h.resourceName))
+	}
+	return response.Success(c, AffectedResult{Affected: affected})
+}
+
+// PurgeDeleted 清空回收站,物理删除符合过滤条件的已软删除资源
+func (h *BaseCrudHandler[T, U]) PurgeDeleted(c echo.Context) error {
+	if err := h.checkSoftDelete(); err != nil {
+		return err
+	}
+	filter, err := sqlx.ParseQueryFilterFromUrlParams(c.QueryParams())
+	if err != nil {
+		return response.BadRequest(err)
+	}
+	affected, err := h.crud.PurgeDeleted(c.Request().Context(), filter)
+	if err != nil {
+		return queryError(err)
+	}
+	return response.Success(c, AffectedResult{Affected: affected})
+}
+
+// requireConditions 检查过滤条件不为空,防止按过滤条件更新或删除时误操作全表
+func (h *BaseCrudHandler[T, U]) requireConditions(filter *sqlx.QueryFilter) error {
+	if filter == nil || (len(filter.Conditions) == 0 && len(filter.Groups) == 0) {
+		return response.BadRequest(fmt.Errorf("按条件修改%s时过滤条件不能为空", h.resourceName))
+	}
+	return nil
+}
+
+// checkSoftDelete 检查资源是否支持软删除
+func (h *BaseCrudHandler[T, U]) checkSoftDelete() error {
+	var table T
+	if !sqlx.IsSoftDelete(table) {
+		return response.BusinessError(fmt.Errorf("%s不支持回收站操作", h.resourceName))
+	}
+	return nil
+}
+
+// parseFieldFilter 解析并校验atts_require/atts_omit参数,返回字段过滤器及最终返回的列
+func (h *BaseCrudHandler[T, U]) parseFieldFilter(c echo.Context) (*sqlx.FieldFilter, []string, error) {
+	fieldFilter, ok := sqlx.ParseFieldFilterFromQuery(c.QueryParams())
+	if !ok {
+		return nil, nil, nil
+	}
+	var table T
+	columns, err := fieldFilter.Columns(table)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fieldFilter, columns, nil
+}
+
+// parseInclude 解析include参数,并在字段过滤器中补充加载关联需要的列(ID和外键)
+func (h *BaseCrudHandler[T, U]) parseInclude(c echo.Context, fieldFilter *sqlx.FieldFilter) ([]string, *sqlx.FieldFilter, error) {
+	include := sqlx.ParseIncludeFromQuery(c.QueryParams())
+	fieldFilter, err := h.includeColumns(include, fieldFilter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return include, fieldFilter, nil
+}
+
+// includeColumns 校验include中的关联,并在字段过滤器中补充加载关联需要的列
+func (h *BaseCrudHandler[T, U]) includeColumns(include []string, fieldFilter *sqlx.FieldFilter) (*sqlx.FieldFilter, error) {
+	if len(include) == 0 {
+		return fieldFilter, nil
+	}
+	var table T
+	names := sqlx.RelationNames(table)
+	for _, name := range include {
+		if !slices.Contains(names, name) {
+			return nil, response.BadRequest(fmt.Errorf("%s不支持关联 %s,可用的关联: %s", h.resourceName, name, strings.Join(names, ",")))
+		}
+	}
+	columns, err := sqlx.RelationColumns(table, include)
+	if err != nil {
+		return nil, response.SystemError(err)
+	}
+	return fieldFilter.WithColumns(columns...), nil
+}
+
+// withRelations 批量加载关联,将记录转换为按选中的列裁剪并附带关联数据的map
+func (h *BaseCrudHandler[T, U]) withRelations(c echo.Context, items []T, columns []string, include []string) ([]map[string]interface{}, error) {
+	relations, err := h.crud.LoadRelations(c.Request().Context(), items, include)
+	if err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		var table T
+		columns = table.Columns()
+	}
+	result := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		result[i] = sqlx.PickFields(item, columns)
+		for name, value := range relations[i] {
+			result[i][name] = value
+		}
+	}
+	return result, nil
+}
+
+// pickItem 按选中的列裁剪单条记录,columns为nil时原样返回
+func pickItem[T sqlx.ITable](item T, columns []string) interface{} {
+	if columns == nil {
+		return item
+	}
+	return sqlx.PickFields(item, columns)
+}
+
+// pickItems 按选中的列裁剪记录列表
+func pickItems[T sqlx.ITable](items []T, columns []string) interface{} {
+	if columns == nil {
+		return items
+	}
+	result := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		result[i] = sqlx.PickFields(item, columns)
+	}
+	return result
+}
+
+// pickPage 按选中的列裁剪分页结果中的记录
+func pickPage[T sqlx.ITable](page *sqlx.PageResult[T], columns []string) interface{} {
+	if columns == nil {
+		return page
+	}
+	result := &sqlx.PageResult[map[string]interface{}]{
+		Items:      make([]map[string]interface{}, len(page.Items)),
+		Total:      page.Total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		HasNext:    page.HasNext,
+		NextCursor: page.NextCursor,
+	}
+	for i, item := range page.Items {
+		result.Items[i] = sqlx.PickFields(item, columns)
+	}
+	return result
+}
+
+// setPageLinks 按RFC 5988设置上一页/下一页的Link响应头
+// 游标分页请求只返回下一页链接
+func setPageLinks(c echo.Context, page, pageSize int, hasNext bool, nextCursor string) {
+	usingCursor := c.QueryParam(sqlx.CursorKey) != ""
+	var links []string
+	if hasNext {
+		next := pageURL(c, map[string]string{"page": strconv.Itoa(page + 1), "page_size": strconv.Itoa(pageSize)})
+		if usingCursor {
+			next = pageURL(c, map[string]string{sqlx.CursorKey: nextCursor, "page_size": strconv.Itoa(pageSize)})
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, next))
+	}
+	if page > 1 && !usingCursor {
+		prev := pageURL(c, map[string]string{"page": strconv.Itoa(page - 1), "page_size": strconv.Itoa(pageSize)})
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, prev))
+	}
+	if len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// findError 将按ID操作单个资源的错误转换为响应错误,记录不存在时返回404,其余见queryError
+func (h *BaseCrudHandler[T, U]) findError(err error) error {
+	if errors.Is(err, sqlx.ErrNotFound) {
+		return response.NotFound(fmt.Errorf("%s不存在", h.resourceName))
+	}
+	return queryError(err)
+}
+
+// invalidQueryErrors 客户端提供的过滤字段、操作符、值、排序或游标无效时数据层返回的错误
+var invalidQueryErrors = []error{
+	sqlx.ErrInvalidField,
+	sqlx.ErrInvalidOperator,
+	sqlx.ErrInvalidValue,
+	sqlx.ErrInvalidLikeChar,
+	sqlx.ErrInvalidLikeType,
+	sqlx.ErrInvalidLogic,
+	sqlx.ErrGroupTooDeep,
+	sqlx.ErrInvalidSort,
+	sqlx.ErrInvalidCursor,
+}
+
+// queryError 将操作资源的错误转换为响应错误,写入内容未通过校验时返回422,
+// 客户端参数无效时返回400,其余按数据库错误处理
+func queryError(err error) error {
+	var fieldErrs sqlx.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		return validationError(err)
+	}
+	for _, target := range invalidQueryErrors {
+		if errors.Is(err, target) {
+			return response.BadRequest(err)
+		}
+	}
+	return response.DatabaseError(err)
+}
+
+// validationError 将校验结果转换为响应错误,逐字段的错误列表放在details中
+func validationError(err error) error {
+	var fieldErrs sqlx.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		return response.ValidationError(err).WithDetails(fieldErrs)
+	}
+	return response.SystemError(err)
+}
+
+// setETag 为包含版本号的资源设置ETag响应头
+func setETag(c echo.Context, item any) {
+	if version, ok := sqlx.VersionOf(item); ok {
+		c.Response().Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+	}
+}
+
+// applyIfMatch 将If-Match请求头中的ETag作为版本号写入更新内容,覆盖请求体中的version
+func applyIfMatch(c echo.Context, item any) error {
+	value := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return nil
+	}
+	if !sqlx.HasVersion(item) {
+		return nil
+	}
+	tag := strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid If-Match header: %s", value)
+	}
+	sqlx.SetVersion(item, version)
+	return nil
+}
+
+// pageURL 基于当前请求地址替换分页参数生成链接
+func pageURL(c echo.Context, params map[string]string) string {
+	u := *c.Request().URL
+	u.Scheme = c.Scheme()
+	u.Host = c.Request().Host
+	query := u.Query()
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"crud/db"
+	sqlx "crud/db/sqlx"
+	"crud/middleware"
+	"crud/pkg/response"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 测试使用的SQLite表结构,与 db/migrations/scheme.sql 对应
+const testSchema = `
+CREATE TABLE authors (
+  id   INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT    NOT NULL,
+  bio  TEXT
+);
+CREATE TABLE books (
+  id        INTEGER PRIMARY KEY AUTOINCREMENT,
+  title     TEXT    NOT NULL,
+  author_id INTEGER NOT NULL REFERENCES authors(id)
+);
+INSERT INTO authors (name, bio) VALUES ('tom', 'cat'), ('jerry', NULL);
+INSERT INTO books (title, author_id) VALUES ('chase', 1), ('escape', 2), ('trap', 1);`
+
+// newTestServer 创建注册了全部路由、使用内存SQLite数据库的服务
+func newTestServer(t *testing.T) *echo.Echo {
+	t.Helper()
+	conn, err := db.NewSQLiteConnector(":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for _, statement := range strings.Split(testSchema, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := conn.Exec(statement); err != nil {
+			t.Fatalf("apply schema: %v", err)
+		}
+	}
+	e := echo.New()
+	e.Use(middleware.ErrorHandler())
+	RegisterRoutes(e, sqlx.NewRepository(conn, sqlx.SQLite))
+	return e
+}
+
+// serve 发送请求,返回状态码和解析后的响应
+func serve(t *testing.T, e *echo.Echo, method, target, body string) (int, response.Response) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	var resp response.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: invalid response %q", method, target, rec.Body.String())
+	}
+	return rec.Code, resp
+}
+
+func TestInvalidQueryParams(t *testing.T) {
+	e := newTestServer(t)
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"valid filter", http.MethodGet, "/authors?name=tom", "", http.StatusOK},
+		{"unknown field", http.MethodGet, "/authors?password=x", "", http.StatusBadRequest},
+		{"unknown field on page", http.MethodGet, "/authors?password=x&page=1", "", http.StatusBadRequest},
+		{"unknown sort field", http.MethodGet, "/authors?sort=-password", "", http.StatusBadRequest},
+		{"unknown sort_field on page", http.MethodGet, "/authors?sort_field=password&page=1", "", http.StatusBadRequest},
+		{"unknown field in group", http.MethodGet, "/authors?or=(name:tom,password:x)", "", http.StatusBadRequest},
+		{"unknown relation field", http.MethodGet, "/books?author.password=x", "", http.StatusBadRequest},
+		{"malformed cursor", http.MethodGet, "/authors?cursor=!", "", http.StatusBadRequest},
+		// 游标中的值数量与排序键不一致
+		{"tampered cursor", http.MethodGet, "/authors?cursor=eyJ2IjpbImEiXSwiaWQiOjF9", "", http.StatusBadRequest},
+		{"delete by unknown field", http.MethodDelete, "/authors?password=x", "", http.StatusBadRequest},
+		{"update by unknown field", http.MethodPatch, "/authors?password=x", `{"bio":"x"}`, http.StatusBadRequest},
+		// 列名两侧的空白和空项被忽略
+		{"upsert trims update columns", http.MethodPut, "/authors?update_columns=%20bio%20,,name", `[{"id":1,"name":"thomas"}]`, http.StatusOK},
+		{"upsert unknown update column", http.MethodPut, "/authors?update_columns=password", `[{"name":"spike"}]`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, resp := serve(t, e, tt.method, tt.target, tt.body); status != tt.want {
+				t.Fatalf("status = %d, want %d, message: %s", status, tt.want, resp.Message)
+			}
+		})
+	}
+}
+
+func TestGetAuthorWithBooks(t *testing.T) {
+	e := newTestServer(t)
+	tests := []struct {
+		name      string
+		target    string
+		wantBooks []string
+	}{
+		{"author with books", "/authors/1/books", []string{"chase", "trap"}},
+		// 路由固定加载books,请求中的include参数不影响结果
+		{"include in query is ignored", "/authors/2/books?include=unknown", []string{"escape"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := serve(t, e, http.MethodGet, tt.target, "")
+			if status != http.StatusOK {
+				t.Fatalf("status = %d, message: %s", status, resp.Message)
+			}
+			data, _ := resp.Data.(map[string]interface{})
+			for _, key := range []string{"id", "name", "bio", "books"} {
+				if _, ok := data[key]; !ok {
+					t.Fatalf("data = %v, missing %q", data, key)
+				}
+			}
+			books, _ := data["books"].([]interface{})
+			var titles []string
+			for _, book := range books {
+				titles = append(titles, book.(map[string]interface{})["title"].(string))
+			}
+			if !reflect.DeepEqual(titles, tt.wantBooks) {
+				t.Fatalf("books = %v, want %v", titles, tt.wantBooks)
+			}
+		})
+	}
+	if status, _ := serve(t, e, http.MethodGet, "/authors/9/books", ""); status != http.StatusNotFound {
+		t.Errorf("missing author status = %d, want 404", status)
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	e := newTestServer(t)
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"valid book", http.MethodPost, "/books", `{"title":"ambush","author_id":2}`, http.StatusOK},
+		{"missing title", http.MethodPost, "/books", `{"author_id":1}`, http.StatusUnprocessableEntity},
+		// author_id为0满足required,由fk规则拒绝
+		{"zero author_id", http.MethodPost, "/books", `{"title":"ambush"}`, http.StatusUnprocessableEntity},
+		{"batch with invalid item", http.MethodPost, "/books/batch", `[{"title":"a","author_id":1},{"author_id":1}]`, http.StatusUnprocessableEntity},
+		{"upsert with invalid item", http.MethodPut, "/books", `[{"author_id":9}]`, http.StatusUnprocessableEntity},
+		{"update with unknown author", http.MethodPut, "/books/1", `{"id":1,"author_id":9}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := serve(t, e, tt.method, tt.target, tt.body)
+			if status != tt.want {
+				t.Fatalf("status = %d, want %d, message: %s", status, tt.want, resp.Message)
+			}
+			if status == http.StatusUnprocessableEntity && resp.Details == nil {
+				t.Fatalf("validation error without details: %s", resp.Message)
+			}
+		})
+	}
+}
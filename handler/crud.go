@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"crud/db/sqlx"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Operation CRUD操作,取值与BaseHandler的方法名一致
+type Operation string
+
+const (
+	OpGetById        Operation = "GetById"
+	OpGetByIds       Operation = "GetByIds"
+	OpGetByFilter    Operation = "GetByFilter"
+	OpCreate         Operation = "Create"
+	OpCreateBatch    Operation = "CreateBatch"
+	OpUpsert         Operation = "Upsert"
+	OpDeleteById     Operation = "DeleteById"
+	OpDeleteByIds    Operation = "DeleteByIds"
+	OpDeleteByFilter Operation = "DeleteByFilter"
+	OpUpdateById     Operation = "UpdateById"
+	OpUpdateByIds    Operation = "UpdateByIds"
+	OpUpdateByFilter Operation = "UpdateByFilter"
+	OpGetDeleted     Operation = "GetDeleted"
+	OpRestoreById    Operation = "RestoreById"
+	OpPurgeDeleted   Operation = "PurgeDeleted"
+)
+
+// CrudOptions 资源路由的注册选项
+type CrudOptions struct {
+	Label      string                         // 资源显示名称,用于错误消息,默认使用name
+	Disable    []Operation                    // 不注册的操作
+	Override   map[Operation]echo.HandlerFunc // 替换默认处理函数的操作
+	Middleware []echo.MiddlewareFunc          // 只作用于该资源路由的中间件
+	Doc        *ApiDoc                        // 不为nil时将资源加入OpenAPI文档
+}
+
+// CrudRoute 资源路由,Path相对于资源路径
+type CrudRoute struct {
+	Operation Operation
+	Method    string
+	Path      string
+}
+
+// CrudRoutes 资源的全部路由,静态路径batch、trash优先于:id匹配,互不冲突
+// ID列表通过查询参数ids传递,例如 ?ids=1&ids=2,过滤条件同样通过查询参数传递
+var CrudRoutes = []CrudRoute{
+	{OpGetByFilter, http.MethodGet, ""},              // 按过滤条件查询,支持分页
+	{OpCreate, http.MethodPost, ""},                  // 创建
+	{OpUpsert, http.MethodPut, ""},                   // 批量插入或更新
+	{OpUpdateByFilter, http.MethodPatch, ""},         // 按过滤条件更新
+	{OpDeleteByFilter, http.MethodDelete, ""},        // 按过滤条件删除
+	{OpGetByIds, http.MethodGet, "/batch"},           // 按ID列表查询
+	{OpCreateBatch, http.MethodPost, "/batch"},       // 批量创建
+	{OpUpdateByIds, http.MethodPatch, "/batch"},      // 按ID列表更新
+	{OpDeleteByIds, http.MethodDelete, "/batch"},     // 按ID列表删除
+	{OpGetDeleted, http.MethodGet, "/trash"},         // 查询回收站
+	{OpPurgeDeleted, http.MethodDelete, "/trash"},    // 清空回收站
+	{OpGetById, http.MethodGet, "/:id"},              // 按ID查询
+	{OpUpdateById, http.MethodPut, "/:id"},           // 按ID更新
+	{OpDeleteById, http.MethodDelete, "/:id"},        // 按ID删除
+	{OpRestoreById, http.MethodPost, "/:id/restore"}, // 从回收站恢复
+}
+
+// softDeleteOperations 只对声明了软删除列的资源注册的操作
+var softDeleteOperations = map[Operation]struct{}{
+	OpGetDeleted:   {},
+	OpRestoreById:  {},
+	OpPurgeDeleted: {},
+}
+
+// RegisterCrud 在group下以name为路径注册资源的全部CRUD路由,返回资源路由组以便追加自定义路由
+// 不支持软删除的资源不注册回收站相关路由
+func RegisterCrud[T sqlx.ITable, U sqlx.ITableUpdate](group *echo.Group, name string, table *sqlx.Table[T], opts CrudOptions) *echo.Group {
+	label := opts.Label
+	if label == "" {
+		label = name
+	}
+	h := NewBaseCrudHandler[T, U](label, table)
+	disabled := make(map[Operation]struct{}, len(opts.Disable))
+	for _, op := range opts.Disable {
+		disabled[op] = struct{}{}
+	}
+	var tableInstance T
+	if !sqlx.IsSoftDelete(tableInstance) {
+		for op := range softDeleteOperations {
+			disabled[op] = struct{}{}
+		}
+	}
+
+	g := group.Group("/"+name, opts.Middleware...)
+	var registered []crudRouteDoc
+	for _, route := range CrudRoutes {
+		if _, ok := disabled[route.Operation]; ok {
+			continue
+		}
+		handlerFunc, ok := opts.Override[route.Operation]
+		if !ok {
+			handlerFunc = crudHandlerFunc[T, U](h, route.Operation)
+		}
+		r := g.Add(route.Method, route.Path, handlerFunc)
+		registered = append(registered, crudRouteDoc{operation: route.Operation, method: r.Method, path: r.Path})
+	}
+	if opts.Doc != nil {
+		addResource[T, U](opts.Doc, table.Repository(), name, label, registered)
+	}
+	return g
+}
+
+// crudHandlerFunc 返回操作对应的BaseHandler方法
+func crudHandlerFunc[T sqlx.ITable, U sqlx.ITableUpdate](h BaseHandler[T, U], op Operation) echo.HandlerFunc {
+	switch op {
+	case OpGetById:
+		return h.GetById
+	case OpGetByIds:
+		return h.GetByIds
+	case OpGetByFilter:
+		return h.GetByFilter
+	case OpCreate:
+		return h.Create
+	case OpCreateBatch:
+		return h.CreateBatch
+	case OpUpsert:
+		return h.Upsert
+	case OpDeleteById:
+		return h.DeleteById
+	case OpDeleteByIds:
+		return h.DeleteByIds
+	case OpDeleteByFilter:
+		return h.DeleteByFilter
+	case OpUpdateById:
+		return h.UpdateById
+	case OpUpdateByIds:
+		return h.UpdateByIds
+	case OpUpdateByFilter:
+		return h.UpdateByFilter
+	case OpGetDeleted:
+		return h.GetDeleted
+	case OpRestoreById:
+		return h.RestoreById
+	case OpPurgeDeleted:
+		return h.PurgeDeleted
+	}
+	return nil
+}
@@ -0,0 +1,487 @@
+package handler
+
+import (
+	"crud/db/sqlx"
+	"crud/pkg/errors"
+	"crud/pkg/openapi"
+	"crud/pkg/response"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ApiDoc 由RegisterCrud注册的资源构建的OpenAPI文档
+// 在CrudOptions.Doc中传入后,资源的路由、结构和参数会自动加入文档
+type ApiDoc struct {
+	doc     *openapi.Document
+	mu      sync.Mutex
+	pending []func() // 全部资源注册后才能生成的内容,例如依赖关联表结构的过滤参数
+}
+
+// NewApiDoc 创建文档,预置统一响应结构、错误码和通用参数
+func NewApiDoc(title, version string) *ApiDoc {
+	doc := openapi.New(title, version)
+	addCommonComponents(doc)
+	return &ApiDoc{doc: doc}
+}
+
+// Document 返回构建的文档,可在此基础上补充自定义接口
+func (d *ApiDoc) Document() *openapi.Document {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, fn := range d.pending {
+		fn()
+	}
+	d.pending = nil
+	return d.doc
+}
+
+// Handler 以JSON格式返回文档,通常挂载在 /openapi.json
+func (d *ApiDoc) Handler(c echo.Context) error {
+	return c.JSON(http.StatusOK, d.Document())
+}
+
+// deferUntilRead 延迟到首次读取文档时执行fn,此时关联表已全部注册
+func (d *ApiDoc) deferUntilRead(fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending = append(d.pending, fn)
+}
+
+// 请求体的类型
+type bodyKind int
+
+const (
+	noBody     bodyKind = iota
+	bodyItem            // 单条记录T
+	bodyItems           // 记录列表[]T
+	bodyUpdate          // 更新内容U
+)
+
+// 响应中data的类型
+type dataKind int
+
+const (
+	dataItem     dataKind = iota // 单条记录T
+	dataItems                    // 记录列表[]T
+	dataList                     // 记录列表或分页结果
+	dataIds                      // 创建的记录ID
+	dataAffected                 // 影响的行数
+)
+
+// operationDoc 操作的文档描述,summary中的%s替换为资源名称
+type operationDoc struct {
+	summary string
+	id      bool     // 路径参数id
+	ids     bool     // 查询参数ids
+	filter  bool     // 过滤条件参数
+	list    bool     // 分页和排序参数
+	fields  bool     // 返回字段参数
+	body    bodyKind // 请求体
+	data    dataKind // 响应数据
+	errors  []string // 可能返回的错误响应,引用components中的响应
+}
+
+var operationDocs = map[Operation]operationDoc{
+	OpGetById:        {summary: "查询单个%s", id: true, fields: true, data: dataItem, errors: []string{"BadRequest", "NotFound"}},
+	OpGetByIds:       {summary: "按ID列表查询%s", ids: true, fields: true, data: dataItems, errors: []string{"BadRequest"}},
+	OpGetByFilter:    {summary: "按过滤条件查询%s", filter: true, list: true, fields: true, data: dataList, errors: []string{"BadRequest"}},
+	OpCreate:         {summary: "创建%s", body: bodyItem, data: dataItem, errors: []string{"BadRequest", "Conflict", "ValidationFailed"}},
+	OpCreateBatch:    {summary: "批量创建%s", body: bodyItems, data: dataIds, errors: []string{"BadRequest", "Conflict", "ValidationFailed"}},
+	OpUpsert:         {summary: "批量插入或更新%s", body: bodyItems, data: dataAffected, errors: []string{"BadRequest", "ValidationFailed"}},
+	OpDeleteById:     {summary: "删除单个%s", id: true, data: dataAffected, errors: []string{"BadRequest", "NotFound", "Conflict"}},
+	OpDeleteByIds:    {summary: "按ID列表删除%s", ids: true, data: dataAffected, errors: []string{"BadRequest", "Conflict"}},
+	OpDeleteByFilter: {summary: "按过滤条件删除%s", filter: true, data: dataAffected, errors: []string{"BadRequest", "Conflict"}},
+	OpUpdateById:     {summary: "更新单个%s", id: true, body: bodyUpdate, data: dataItem, errors: []string{"BadRequest", "NotFound", "Conflict", "ValidationFailed"}},
+	OpUpdateByIds:    {summary: "按ID列表更新%s", ids: true, body: bodyUpdate, data: dataAffected, errors: []string{"BadRequest", "ValidationFailed"}},
+	OpUpdateByFilter: {summary: "按过滤条件更新%s", filter: true, body: bodyUpdate, data: dataAffected, errors: []string{"BadRequest", "ValidationFailed"}},
+	OpGetDeleted:     {summary: "查询回收站中的%s", filter: true, list: true, fields: true, data: dataList, errors: []string{"BadRequest"}},
+	OpRestoreById:    {summary: "从回收站恢复%s", id: true, data: dataAffected, errors: []string{"BadRequest", "NotFound"}},
+	OpPurgeDeleted:   {summary: "清空%s回收站", filter: true, data: dataAffected, errors: []string{"BadRequest"}},
+}
+
+// errorResponses 错误响应,键为components中的名称
+var errorResponses = map[string]int{
+	"BadRequest":         http.StatusBadRequest,
+	"NotFound":           http.StatusNotFound,
+	"Conflict":           http.StatusConflict,
+	"ValidationFailed":   http.StatusUnprocessableEntity,
+	"InternalError":      http.StatusInternalServerError,
+	"ServiceUnavailable": http.StatusServiceUnavailable,
+}
+
+// 列表接口的分页、排序和返回字段参数
+var listParameters = []*openapi.Parameter{
+	{Name: "page", In: "query", Description: "页码,从1开始,与page_size一起使用时返回分页结果", Schema: &openapi.Schema{Type: "integer", Format: "int32"}},
+	{Name: "page_size", In: "query", Description: "每页记录数,默认10", Schema: &openapi.Schema{Type: "integer", Format: "int32"}},
+	{Name: sqlx.CursorKey, In: "query", Description: "游标分页位置,取自上一页结果的next_cursor", Schema: &openapi.Schema{Type: "string"}},
+	{Name: "sort", In: "query", Description: "多字段排序,字段前加-表示降序,例如 -created_at,name", Schema: &openapi.Schema{Type: "string"}},
+	{Name: "sort_field", In: "query", Description: "排序字段", Schema: &openapi.Schema{Type: "string"}},
+	{Name: "sort_order", In: "query", Description: "排序方式", Schema: &openapi.Schema{Type: "string", Enum: []interface{}{"ASC", "DESC"}}},
+}
+
+var fieldParameters = []*openapi.Parameter{
+	{Name: sqlx.RequiredFieldsKey, In: "query", Description: "只返回指定的字段,逗号分隔", Schema: &openapi.Schema{Type: "string"}},
+	{Name: sqlx.OmittedFieldsKey, In: "query", Description: "不返回指定的字段,逗号分隔", Schema: &openapi.Schema{Type: "string"}},
+}
+
+var groupParameters = []*openapi.Parameter{
+	{Name: "and", In: "query", Description: "AND条件分组,例如 and=(name_like:tom,bio_null:false)", Schema: &openapi.Schema{Type: "string"}},
+	{Name: "or", In: "query", Description: "OR条件分组,例如 or=(name_like:tom,bio_like:tom)", Schema: &openapi.Schema{Type: "string"}},
+	{Name: "not", In: "query", Description: "取反的条件分组,例如 not=(name:tom)", Schema: &openapi.Schema{Type: "string"}},
+}
+
+// addCommonComponents 添加所有资源共用的结构、参数和响应
+func addCommonComponents(doc *openapi.Document) {
+	schemas := doc.Components.Schemas
+
+	codes := errors.Codes()
+	codeEnum := make([]interface{}, len(codes))
+	codeLines := make([]string, len(codes))
+	for i, code := range codes {
+		codeEnum[i] = int(code)
+		codeLines[i] = fmt.Sprintf("%d: %s", code, errors.GetMessage(code))
+	}
+	schemas["ErrorCode"] = &openapi.Schema{Type: "integer", Enum: codeEnum, Description: "业务错误码\n" + strings.Join(codeLines, "\n")}
+
+	envelope := openapi.SchemaOf(reflect.TypeOf(response.Response{}))
+	envelope.Properties["code"] = openapi.SchemaRef("ErrorCode")
+	envelope.Required = []string{"code", "code_desc", "message", "timestamp"}
+	schemas["Response"] = envelope
+
+	schemas["FieldError"] = openapi.SchemaOf(reflect.TypeOf(sqlx.FieldError{}))
+	schemas["DBErrorDetail"] = openapi.SchemaOf(reflect.TypeOf(errors.DBErrorDetail{}))
+	schemas["ErrorResponse"] = &openapi.Schema{AllOf: []*openapi.Schema{
+		openapi.SchemaRef("Response"),
+		{Type: "object", Properties: map[string]*openapi.Schema{
+			"details": {
+				Description: "错误详情,校验错误为逐字段的错误列表,数据库错误为错误号及相关的列和约束",
+				OneOf:       []*openapi.Schema{openapi.ArrayOf(openapi.SchemaRef("FieldError")), openapi.SchemaRef("DBErrorDetail")},
+			},
+		}},
+	}}
+	schemas["AffectedResult"] = openapi.SchemaOf(reflect.TypeOf(AffectedResult{}))
+	schemas["GroupIds"] = openapi.SchemaOf(reflect.TypeOf(GroupIds{}))
+
+	params := doc.Components.Parameters
+	params["id"] = &openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "integer", Format: "int64"}}
+	params["ids"] = &openapi.Parameter{Name: "ids", In: "query", Required: true, Description: "ID列表,例如 ?ids=1&ids=2",
+		Schema: openapi.ArrayOf(&openapi.Schema{Type: "integer", Format: "int64"})}
+	params[UpdateColumnsKey] = &openapi.Parameter{Name: UpdateColumnsKey, In: "query", Description: "冲突时需要更新的列,逗号分隔,为空时更新除id外的全部列",
+		Schema: &openapi.Schema{Type: "string"}}
+	params["If-Match"] = &openapi.Parameter{Name: "If-Match", In: "header", Description: "记录当前的版本号,取自查询结果的ETag",
+		Schema: &openapi.Schema{Type: "string"}}
+	for _, group := range [][]*openapi.Parameter{listParameters, fieldParameters, groupParameters} {
+		for _, param := range group {
+			params[param.Name] = param
+		}
+	}
+
+	for name, status := range errorResponses {
+		var lines []string
+		for _, code := range codes {
+			if code != errors.Success && errors.New(code, "").HTTPStatus() == status {
+				lines = append(lines, fmt.Sprintf("%d: %s", code, errors.GetMessage(code)))
+			}
+		}
+		doc.Components.Responses[name] = &openapi.Response{
+			Description: http.StatusText(status) + ",错误码 " + strings.Join(lines, "; "),
+			Content:     openapi.JSON(openapi.SchemaRef("ErrorResponse")),
+		}
+	}
+}
+
+// crudRouteDoc 已注册的资源路由
+type crudRouteDoc struct {
+	operation Operation
+	method    string
+	path      string
+}
+
+// addResource 将资源的结构、过滤参数和已注册的路由加入文档,关联表从资源所在的数据库句柄repo中查找
+func addResource[T sqlx.ITable, U sqlx.ITableUpdate](d *ApiDoc, repo *sqlx.Repository, name, label string, routes []crudRouteDoc) {
+	var item T
+	var update U
+	itemName := reflect.TypeOf(item).Name()
+	updateName := reflect.TypeOf(update).Name()
+	doc := d.doc
+	doc.Components.Schemas[itemName] = modelSchema(item, item.ColumnsMap(), true)
+	columnsMap := make(map[string]struct{}, len(update.Columns()))
+	for _, column := range update.Columns() {
+		columnsMap[column] = struct{}{}
+	}
+	doc.Components.Schemas[updateName] = modelSchema(update, columnsMap, false)
+	pageSchema := openapi.SchemaOf(reflect.TypeOf(sqlx.PageResult[T]{}))
+	pageSchema.Properties["items"] = openapi.ArrayOf(openapi.SchemaRef(itemName))
+	doc.Components.Schemas[itemName+"Page"] = pageSchema
+	filterParams := addFilterParameters(doc, itemName, item)
+	doc.Tags = append(doc.Tags, openapi.Tag{Name: name, Description: label})
+
+	versioned := sqlx.HasVersion(item)
+	relations := sqlx.RelationNames(item)
+	relationEnum := make([]interface{}, len(relations))
+	for i, relation := range relations {
+		relationEnum[i] = relation
+	}
+	explode := false
+	includeParam := &openapi.Parameter{Name: sqlx.IncludeKey, In: "query",
+		Description: "加载关联的记录,逗号分隔,每个关联批量查询一次",
+		Style:       "form",
+		Explode:     &explode,
+		Schema:      openapi.ArrayOf(&openapi.Schema{Type: "string", Enum: relationEnum})}
+	var filterOps []*openapi.Operation
+	for _, route := range routes {
+		opDoc := operationDocs[route.operation]
+		op := &openapi.Operation{
+			Tags:        []string{name},
+			Summary:     fmt.Sprintf(opDoc.summary, label),
+			OperationID: string(route.operation) + "_" + name,
+			Responses:   make(map[string]*openapi.Response),
+		}
+		if opDoc.id {
+			op.Parameters = append(op.Parameters, openapi.ParameterRef("id"))
+		}
+		if opDoc.ids {
+			op.Parameters = append(op.Parameters, openapi.ParameterRef("ids"))
+		}
+		if opDoc.filter {
+			filterOps = append(filterOps, op)
+			op.Parameters = append(op.Parameters, filterParams...)
+			for _, param := range groupParameters {
+				op.Parameters = append(op.Parameters, openapi.ParameterRef(param.Name))
+			}
+		}
+		if opDoc.list {
+			for _, param := range listParameters {
+				op.Parameters = append(op.Parameters, openapi.ParameterRef(param.Name))
+			}
+		}
+		if opDoc.fields {
+			for _, param := range fieldParameters {
+				op.Parameters = append(op.Parameters, openapi.ParameterRef(param.Name))
+			}
+			if len(relations) > 0 {
+				op.Parameters = append(op.Parameters, includeParam)
+			}
+		}
+		if route.operation == OpUpsert {
+			op.Parameters = append(op.Parameters, openapi.ParameterRef(UpdateColumnsKey))
+		}
+		if route.operation == OpUpdateById && versioned {
+			op.Parameters = append(op.Parameters, openapi.ParameterRef("If-Match"))
+		}
+
+		switch opDoc.body {
+		case bodyItem:
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.SchemaRef(itemName))}
+		case bodyItems:
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.ArrayOf(openapi.SchemaRef(itemName)))}
+		case bodyUpdate:
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.SchemaRef(updateName))}
+		}
+
+		var data *openapi.Schema
+		switch opDoc.data {
+		case dataItem:
+			data = openapi.SchemaRef(itemName)
+		case dataItems:
+			data = openapi.ArrayOf(openapi.SchemaRef(itemName))
+		case dataList:
+			data = &openapi.Schema{
+				Description: "不带分页参数时返回记录列表,带分页参数时返回分页结果",
+				OneOf:       []*openapi.Schema{openapi.ArrayOf(openapi.SchemaRef(itemName)), openapi.SchemaRef(itemName + "Page")},
+			}
+		case dataIds:
+			data = openapi.SchemaRef("GroupIds")
+		case dataAffected:
+			data = openapi.SchemaRef("AffectedResult")
+		}
+		success := &openapi.Response{
+			Description: "成功",
+			Content: openapi.JSON(&openapi.Schema{AllOf: []*openapi.Schema{
+				openapi.SchemaRef("Response"),
+				{Type: "object", Properties: map[string]*openapi.Schema{"data": data}},
+			}}),
+		}
+		if versioned && opDoc.data == dataItem && route.operation != OpCreate {
+			success.Headers = map[string]*openapi.Header{"ETag": {Description: "记录当前的版本号", Schema: &openapi.Schema{Type: "string"}}}
+		}
+		if opDoc.data == dataList {
+			success.Headers = map[string]*openapi.Header{"Link": {Description: "分页查询时的上一页/下一页链接", Schema: &openapi.Schema{Type: "string"}}}
+		}
+		op.Responses[strconv.Itoa(http.StatusOK)] = success
+		for _, errName := range opDoc.errors {
+			op.Responses[strconv.Itoa(errorResponses[errName])] = openapi.ResponseRef(errName)
+		}
+		op.Responses[strconv.Itoa(http.StatusInternalServerError)] = openapi.ResponseRef("InternalError")
+		op.Responses[strconv.Itoa(http.StatusServiceUnavailable)] = openapi.ResponseRef("ServiceUnavailable")
+
+		doc.AddOperation(route.method, openapiPath(route.path), op)
+	}
+	if len(relations) > 0 && len(filterOps) > 0 {
+		// 关联表可能在本资源之后注册,关联过滤参数在读取文档时生成
+		d.deferUntilRead(func() {
+			relationParams := addRelationFilterParameters(doc, repo, itemName, item)
+			for _, op := range filterOps {
+				op.Parameters = append(op.Parameters, relationParams...)
+			}
+		})
+	}
+}
+
+// modelSchema 根据db标签在columnsMap中的字段生成结构,校验规则转换为长度、取值范围等约束
+func modelSchema(item any, columnsMap map[string]struct{}, withRequired bool) *openapi.Schema {
+	t := reflect.TypeOf(item)
+	schema := &openapi.Schema{Type: "object", Properties: make(map[string]*openapi.Schema)}
+	rules, _ := sqlx.FieldRules(item)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		column := strings.Split(field.Tag.Get("db"), ",")[0]
+		if _, ok := columnsMap[column]; !ok {
+			continue
+		}
+		name, ok := openapi.JSONName(field)
+		if !ok {
+			continue
+		}
+		property := openapi.SchemaOf(field.Type)
+		applyRules(property, rules[name])
+		if _, ok := rules[name]["required"]; ok && withRequired {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// applyRules 将校验规则转换为结构约束
+func applyRules(schema *openapi.Schema, rules map[string]string) {
+	for name, param := range rules {
+		switch name {
+		case "min", "max":
+			value, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			if schema.Type == "string" {
+				length := int(value)
+				if name == "min" {
+					schema.MinLength = &length
+				} else {
+					schema.MaxLength = &length
+				}
+			} else if schema.Type == "integer" || schema.Type == "number" {
+				if name == "min" {
+					schema.Minimum = &value
+				} else {
+					schema.Maximum = &value
+				}
+			}
+		case "maxbytes":
+			// JSON Schema的maxLength按字符计,字节数限制只能写在描述中
+			schema.Description = "最多 " + param + " 字节"
+		case "oneof":
+			for _, option := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, option)
+			}
+		case "regex":
+			schema.Pattern = param
+		case "fk":
+			schema.Description = "引用 " + param
+		}
+	}
+}
+
+// addFilterParameters 为资源的每一列生成过滤参数,返回参数引用
+func addFilterParameters(doc *openapi.Document, itemName string, item sqlx.ITable) []*openapi.Parameter {
+	return addColumnFilterParameters(doc, itemName, "", item)
+}
+
+// addRelationFilterParameters 为资源声明的每个关联生成按关联表的列过滤的参数,例如 author.name_like
+// 关联表通过资源所在的数据库句柄查找,未注册时跳过该关联
+func addRelationFilterParameters(doc *openapi.Document, repo *sqlx.Repository, itemName string, item sqlx.ITable) []*openapi.Parameter {
+	relations, err := sqlx.RelationsOf(item)
+	if err != nil {
+		return nil
+	}
+	var refs []*openapi.Parameter
+	for _, name := range sqlx.RelationNames(item) {
+		related, err := repo.TableOf(relations[name].Table)
+		if err != nil {
+			continue
+		}
+		refs = append(refs, addColumnFilterParameters(doc, itemName, name+".", related)...)
+	}
+	return refs
+}
+
+// addColumnFilterParameters 为table的每一列生成过滤参数,参数名为prefix加列名和操作符后缀
+func addColumnFilterParameters(doc *openapi.Document, itemName string, prefix string, item sqlx.ITable) []*openapi.Parameter {
+	operators := sqlx.FilterOperators()
+	suffixes := make([]string, 0, len(operators))
+	for suffix := range operators {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+
+	columnTypes := make(map[string]reflect.Type)
+	t := reflect.TypeOf(item)
+	for i := 0; i < t.NumField(); i++ {
+		column := strings.Split(t.Field(i).Tag.Get("db"), ",")[0]
+		columnTypes[column] = t.Field(i).Type
+	}
+
+	var refs []*openapi.Parameter
+	add := func(name string, description string, schema *openapi.Schema) {
+		name = prefix + name
+		key := itemName + "." + name
+		doc.Components.Parameters[key] = &openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+		refs = append(refs, openapi.ParameterRef(key))
+	}
+	for _, column := range item.Columns() {
+		valueSchema := &openapi.Schema{Type: "string"}
+		if columnType, ok := columnTypes[column]; ok {
+			if schema := openapi.SchemaOf(columnType); schema.Type != "object" {
+				valueSchema = schema
+				valueSchema.Nullable = false
+			}
+		}
+		label := prefix + column
+		add(column, label+" = 值", valueSchema)
+		for _, suffix := range suffixes {
+			var schema *openapi.Schema
+			var description string
+			switch operator := operators[suffix]; operator {
+			case "IN", "NOT IN":
+				schema, description = &openapi.Schema{Type: "string"}, label+" "+operator+" (逗号分隔的值列表)"
+			case "BETWEEN":
+				schema, description = &openapi.Schema{Type: "string"}, label+" BETWEEN 最小值 AND 最大值,逗号分隔"
+			case "IS NULL":
+				schema, description = &openapi.Schema{Type: "boolean"}, "true时"+label+" IS NULL,false时"+label+" IS NOT NULL"
+			case "LIKE":
+				schema, description = &openapi.Schema{Type: "string"}, label+" LIKE 值,不区分大小写"
+			default:
+				schema, description = valueSchema, label+" "+operator+" 值"
+			}
+			add(column+"_"+suffix, description, schema)
+		}
+	}
+	return refs
+}
+
+// openapiPath 将echo的路径参数 :id 转换为OpenAPI的 {id}
+func openapiPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
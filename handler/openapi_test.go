@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"crud/db"
+	sqlc "crud/db/sqlc"
+	"crud/db/sqlx"
+	"crud/pkg/openapi"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// operationParameters 按名称返回接口的参数,引用的参数从components中解析
+func operationParameters(doc *openapi.Document, op *openapi.Operation) map[string]*openapi.Parameter {
+	params := make(map[string]*openapi.Parameter, len(op.Parameters))
+	for _, param := range op.Parameters {
+		if param.Ref != "" {
+			param = doc.Components.Parameters[param.Ref[len("#/components/parameters/"):]]
+		}
+		params[param.Name] = param
+	}
+	return params
+}
+
+func TestApiDocRelations(t *testing.T) {
+	conn, err := db.NewSQLiteConnector(":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	repo := sqlx.NewRepository(conn, sqlx.SQLite)
+
+	d := NewApiDoc("test", "1.0.0")
+	// books在关联的authors注册之前加入文档,关联过滤参数在读取文档时生成
+	addResource[sqlc.Book, sqlc.BookUpdate](d, repo, "books", "书", []crudRouteDoc{
+		{operation: OpGetByFilter, method: http.MethodGet, path: "/books"},
+		{operation: OpGetById, method: http.MethodGet, path: "/books/:id"},
+	})
+	sqlx.Model[sqlc.Author](repo)
+	sqlx.Model[sqlc.Book](repo)
+	doc := d.Document()
+
+	list := operationParameters(doc, doc.Paths["/books"]["get"])
+	get := operationParameters(doc, doc.Paths["/books/{id}"]["get"])
+	tests := []struct {
+		name   string
+		params map[string]*openapi.Parameter
+		param  string
+		want   bool
+	}{
+		{"column filter", list, "title_like", true},
+		{"relation filter", list, "author.name", true},
+		{"relation filter with operator", list, "author.name_like", true},
+		{"relation of relation is not expanded", list, "author.books.title", false},
+		{"get by id has no filters", get, "author.name", false},
+		{"include on list", list, sqlx.IncludeKey, true},
+		{"include on get by id", get, sqlx.IncludeKey, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.params[tt.param]; ok != tt.want {
+				t.Fatalf("parameter %q present = %v, want %v", tt.param, ok, tt.want)
+			}
+		})
+	}
+
+	include := get[sqlx.IncludeKey]
+	if include.Style != "form" || include.Explode == nil || *include.Explode {
+		t.Errorf("include style = %q explode = %v, want form and false", include.Style, include.Explode)
+	}
+	if include.Schema.Items == nil || !reflect.DeepEqual(include.Schema.Items.Enum, []interface{}{"author"}) {
+		t.Errorf("include items = %+v, want enum [author]", include.Schema.Items)
+	}
+
+	// 重复读取不会重复追加参数
+	count := len(doc.Paths["/books"]["get"].Parameters)
+	if d.Document(); len(doc.Paths["/books"]["get"].Parameters) != count {
+		t.Errorf("parameters were appended again on the second read")
+	}
+}
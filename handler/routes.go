@@ -1,31 +1,29 @@
 package handler
 
 import (
-	"database/sql"
+	sqlc "crud/db/sqlc"
+	sqlx "crud/db/sqlx"
 
 	"github.com/labstack/echo/v4"
 )
 
-// RegisterRoutes 注册所有API路由
-func RegisterRoutes(e *echo.Echo, dbConn *sql.DB) {
+// RegisterRoutes 注册所有API路由,处理器使用repo对应的数据库,返回由注册的资源构建的OpenAPI文档
+func RegisterRoutes(e *echo.Echo, repo *sqlx.Repository) *ApiDoc {
 	// 初始化处理器
-	author = NewAuthorApi(dbConn)
-	book = NewBookApi(dbConn)
+	author := NewAuthorApi(repo)
+	doc := NewApiDoc("crud", "1.0.0")
 
 	e.GET("/ping", PingHandler)
+	e.GET("/openapi.json", doc.Handler) // OpenAPI文档
+
+	api := e.Group("")
 
 	// 作者相关路由
-	e.GET("/authors", author.GetAll)                       // 获取所有作者
-	e.GET("/authors/:ids", author.GetByIds)                // 获取多个作者
-	e.POST("/authors", author.Create)                      // 创建作者
-	e.DELETE("/authors/:id", author.DeleteById)            // 删除单个作者
-	e.PUT("/authors/:id", author.UpdateById)               // 更新作者信息
-	e.GET("/authors/:id/books", author.GetAuthorWithBooks) // 获取作者及其书籍
+	authors := RegisterCrud[sqlc.Author, sqlc.AuthorUpdate](api, "authors", author.crud, CrudOptions{Label: "作者", Doc: doc})
+	authors.GET("/:id/books", author.GetAuthorWithBooks) // 获取作者及其书籍
 
 	// 书籍相关路由
-	e.GET("/books", book.GetAll)            // 获取所有书籍
-	e.GET("/books/:ids", book.GetByIds)     // 获取多个书籍
-	e.POST("/books", book.Create)           // 创建书籍
-	e.DELETE("/books/:id", book.DeleteById) // 删除单个书籍
-	e.PUT("/books/:id", book.UpdateById)    // 更新书籍信息
+	RegisterCrud[sqlc.Book, sqlc.BookUpdate](api, "books", sqlx.Model[sqlc.Book](repo), CrudOptions{Label: "书籍", Doc: doc})
+
+	return doc
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	sqlx "crud/db/sqlx"
 	"crud/handler"
 	"crud/middleware"
 	"database/sql"
@@ -11,8 +12,8 @@ import (
 )
 
 func main() {
-	// 连接数据库
-	db, err := sql.Open("mysql", "root:123456@tcp(localhost:3306)/crud?charset=utf8mb4&parseTime=True&loc=Local")
+	// 连接数据库,clientFoundRows使UPDATE返回匹配行数而不是实际变更行数
+	db, err := sql.Open("mysql", "root:123456@tcp(localhost:3306)/crud?charset=utf8mb4&parseTime=True&loc=Local&clientFoundRows=true")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -28,7 +29,7 @@ func main() {
 	e.Use(middleware.ErrorHandler()) // 错误处理中间件
 
 	// 注册路由
-	handler.RegisterRoutes(e, db)
+	handler.RegisterRoutes(e, sqlx.NewRepository(db, sqlx.MySQL))
 
 	// 启动服务器
 	e.Logger.Fatal(e.Start(":8080"))
@@ -29,6 +29,7 @@ func HandleError(err error, c echo.Context) {
 		message  string
 		status   int
 		codeDesc string
+		details  interface{}
 	)
 
 	switch e := err.(type) {
@@ -37,6 +38,7 @@ func HandleError(err error, c echo.Context) {
 		code = int(e.Code)
 		message = e.Error()
 		codeDesc = errors.GetMessage(e.Code)
+		details = e.Details
 	case *echo.HTTPError:
 		status = e.Code
 		code = int(errors.ErrSystem)
@@ -54,5 +56,6 @@ func HandleError(err error, c echo.Context) {
 		Message:   message,
 		Timestamp: time.Now().Unix(),
 		CodeDesc:  codeDesc,
+		Details:   details,
 	})
 }
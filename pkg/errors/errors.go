@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"sort"
 )
 
 // ErrorCode 错误码类型
@@ -18,6 +19,7 @@ const (
 	ErrCache
 	ErrNetwork
 	ErrServiceUnavailable
+	ErrRetryable
 )
 
 // 业务级错误码 (2000-2999)
@@ -46,6 +48,7 @@ var messages = map[ErrorCode]string{
 	ErrCache:              "缓存错误",
 	ErrNetwork:            "网络错误",
 	ErrServiceUnavailable: "服务不可用",
+	ErrRetryable:          "请稍后重试",
 	ErrInvalidParams:      "无效的参数",
 	ErrValidation:         "验证错误",
 	ErrBusiness:           "业务错误",
@@ -58,6 +61,16 @@ var messages = map[ErrorCode]string{
 	ErrInvalidToken:       "无效的令牌",
 }
 
+// Codes 返回所有已定义的错误码,按错误码升序排列
+func Codes() []ErrorCode {
+	codes := make([]ErrorCode, 0, len(messages))
+	for code := range messages {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
 // GetMessage 获取错误码对应的中文消息
 func GetMessage(code ErrorCode) string {
 	if msg, ok := messages[code]; ok {
@@ -68,9 +81,10 @@ func GetMessage(code ErrorCode) string {
 
 // Error 统一错误结构
 type Error struct {
-	Code    ErrorCode // 错误码
-	Message string    // 错误消息
-	Err     error     // 原始错误
+	Code    ErrorCode   // 错误码
+	Message string      // 错误消息
+	Err     error       // 原始错误
+	Details interface{} // 错误详情,例如逐字段的校验错误
 }
 
 // New 创建新的错误
@@ -109,6 +123,12 @@ func (e *Error) Unwrap() error {
 	return e.Err
 }
 
+// WithDetails 附加错误详情,随响应一并返回
+func (e *Error) WithDetails(details interface{}) *Error {
+	e.Details = details
+	return e
+}
+
 // GetMessage 获取错误消息
 func (e *Error) GetMessage() string {
 	return e.Message
@@ -125,7 +145,7 @@ func (e *Error) HTTPStatus() int {
 		return http.StatusServiceUnavailable
 	case ErrNetwork:
 		return http.StatusBadGateway
-	case ErrServiceUnavailable:
+	case ErrServiceUnavailable, ErrRetryable:
 		return http.StatusServiceUnavailable
 	case ErrInvalidParams:
 		return http.StatusBadRequest
@@ -172,6 +192,14 @@ func IsBusinessError(err error) bool {
 	return false
 }
 
+// IsRetryable 判断是否是可重试的错误,例如死锁或锁等待超时
+func IsRetryable(err error) bool {
+	if e, ok := err.(*Error); ok {
+		return e.Code == ErrRetryable
+	}
+	return false
+}
+
 // IsUnauthorized 判断是否是未授权错误
 func IsUnauthorized(err error) bool {
 	if e, ok := err.(*Error); ok {
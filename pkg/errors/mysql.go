@@ -0,0 +1,103 @@
+package errors
+
+import (
+	stderrors "errors"
+	"regexp"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL错误号
+const (
+	mysqlDupEntry            = 1062 // 唯一键冲突
+	mysqlRowIsReferenced     = 1451 // 删除或修改被外键引用的记录
+	mysqlNoReferencedRow     = 1452 // 外键引用的记录不存在
+	mysqlDataTooLong         = 1406 // 数据超出列长度
+	mysqlBadNull             = 1048 // 非空列写入NULL
+	mysqlNoDefault           = 1364 // 非空列没有默认值
+	mysqlOutOfRange          = 1264 // 数值超出范围
+	mysqlTruncatedWrongValue = 1366 // 数据类型不正确
+	mysqlLockDeadlock        = 1213 // 死锁
+	mysqlLockWaitTimeout     = 1205 // 锁等待超时
+)
+
+var (
+	dupEntryRe   = regexp.MustCompile(`for key '([^']+)'`)
+	foreignKeyRe = regexp.MustCompile("CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`\\)")
+	columnRe     = regexp.MustCompile(`(?:column|Column|Field) '([^']+)'`)
+)
+
+// DBErrorDetail 数据库错误详情,随响应一并返回
+type DBErrorDetail struct {
+	Number     uint16 `json:"number,omitempty"`     // MySQL错误号
+	SQLState   string `json:"sqlstate,omitempty"`   // PostgreSQL错误码
+	Column     string `json:"column,omitempty"`     // 相关的列
+	Constraint string `json:"constraint,omitempty"` // 相关的唯一键或外键约束
+	Retryable  bool   `json:"retryable,omitempty"`  // 是否可以重试
+}
+
+// FromDB 将数据库错误转换为业务错误
+// err链中已包含*Error时直接返回,可识别的MySQL、PostgreSQL错误按错误码转换,其他错误返回nil
+func FromDB(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if stderrors.As(err, &e) {
+		return e
+	}
+	if e := FromMySQL(err); e != nil {
+		return e
+	}
+	return FromPostgres(err)
+}
+
+// FromMySQL 按错误号将MySQL驱动错误转换为业务错误,无法识别时返回nil
+func FromMySQL(err error) *Error {
+	var mysqlErr *mysql.MySQLError
+	if !stderrors.As(err, &mysqlErr) {
+		return nil
+	}
+	detail := DBErrorDetail{Number: mysqlErr.Number}
+	var code ErrorCode
+	var message string
+	switch mysqlErr.Number {
+	case mysqlDupEntry:
+		code, message = ErrDuplicate, "记录已存在"
+		detail.Constraint = submatch(dupEntryRe, mysqlErr.Message, 1)
+	case mysqlRowIsReferenced:
+		code, message = ErrConflict, "记录被其他数据引用,无法删除或修改"
+		detail.Constraint = submatch(foreignKeyRe, mysqlErr.Message, 1)
+		detail.Column = submatch(foreignKeyRe, mysqlErr.Message, 2)
+	case mysqlNoReferencedRow:
+		code, message = ErrValidation, "引用的记录不存在"
+		detail.Constraint = submatch(foreignKeyRe, mysqlErr.Message, 1)
+		detail.Column = submatch(foreignKeyRe, mysqlErr.Message, 2)
+	case mysqlDataTooLong:
+		code, message = ErrValidation, "数据超出长度限制"
+		detail.Column = submatch(columnRe, mysqlErr.Message, 1)
+	case mysqlBadNull, mysqlNoDefault:
+		code, message = ErrValidation, "缺少必填字段"
+		detail.Column = submatch(columnRe, mysqlErr.Message, 1)
+	case mysqlOutOfRange:
+		code, message = ErrValidation, "数值超出范围"
+		detail.Column = submatch(columnRe, mysqlErr.Message, 1)
+	case mysqlTruncatedWrongValue:
+		code, message = ErrValidation, "数据格式不正确"
+		detail.Column = submatch(columnRe, mysqlErr.Message, 1)
+	case mysqlLockDeadlock, mysqlLockWaitTimeout:
+		code, message = ErrRetryable, "数据库繁忙,请稍后重试"
+		detail.Retryable = true
+	default:
+		return nil
+	}
+	return Wrap(code, message, err).WithDetails(detail)
+}
+
+// submatch 返回正则表达式第n个分组的匹配结果,未匹配时返回空字符串
+func submatch(re *regexp.Regexp, s string, n int) string {
+	if match := re.FindStringSubmatch(s); len(match) > n {
+		return match[n]
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestFromMySQL(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   ErrorCode
+		wantDetail DBErrorDetail
+	}{
+		{
+			name:       "duplicate entry",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'tom' for key 'authors.name'"},
+			wantCode:   ErrDuplicate,
+			wantDetail: DBErrorDetail{Number: 1062, Constraint: "authors.name"},
+		},
+		{
+			name: "row is referenced",
+			err: &mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row: a foreign key constraint fails " +
+				"(`crud`.`books`, CONSTRAINT `books_ibfk_1` FOREIGN KEY (`author_id`) REFERENCES `authors` (`id`))"},
+			wantCode:   ErrConflict,
+			wantDetail: DBErrorDetail{Number: 1451, Constraint: "books_ibfk_1", Column: "author_id"},
+		},
+		{
+			name: "no referenced row",
+			err: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails " +
+				"(`crud`.`books`, CONSTRAINT `books_ibfk_1` FOREIGN KEY (`author_id`) REFERENCES `authors` (`id`))"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{Number: 1452, Constraint: "books_ibfk_1", Column: "author_id"},
+		},
+		{
+			name:       "data too long",
+			err:        &mysql.MySQLError{Number: 1406, Message: "Data too long for column 'name' at row 1"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{Number: 1406, Column: "name"},
+		},
+		{
+			name:       "bad null",
+			err:        &mysql.MySQLError{Number: 1048, Message: "Column 'title' cannot be null"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{Number: 1048, Column: "title"},
+		},
+		{
+			name:       "no default",
+			err:        &mysql.MySQLError{Number: 1364, Message: "Field 'title' doesn't have a default value"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{Number: 1364, Column: "title"},
+		},
+		{
+			name:       "out of range",
+			err:        &mysql.MySQLError{Number: 1264, Message: "Out of range value for column 'author_id' at row 1"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{Number: 1264, Column: "author_id"},
+		},
+		{
+			name:       "truncated wrong value",
+			err:        &mysql.MySQLError{Number: 1366, Message: "Incorrect integer value: 'x' for column 'author_id' at row 1"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{Number: 1366, Column: "author_id"},
+		},
+		{
+			name:       "deadlock",
+			err:        &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"},
+			wantCode:   ErrRetryable,
+			wantDetail: DBErrorDetail{Number: 1213, Retryable: true},
+		},
+		{
+			name:       "wrapped lock wait timeout",
+			err:        fmt.Errorf("failed to update rows: %w", &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}),
+			wantCode:   ErrRetryable,
+			wantDetail: DBErrorDetail{Number: 1205, Retryable: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromMySQL(tt.err)
+			if got == nil {
+				t.Fatal("FromMySQL returned nil")
+			}
+			if got.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", got.Code, tt.wantCode)
+			}
+			if !reflect.DeepEqual(got.Details, tt.wantDetail) {
+				t.Errorf("details = %+v, want %+v", got.Details, tt.wantDetail)
+			}
+			if got.Err != tt.err {
+				t.Errorf("the original error is not wrapped")
+			}
+		})
+	}
+
+	for _, err := range []error{
+		&mysql.MySQLError{Number: 1146, Message: "Table 'crud.x' doesn't exist"},
+		stderrors.New("connection refused"),
+		nil,
+	} {
+		if got := FromMySQL(err); got != nil {
+			t.Errorf("FromMySQL(%v) = %v, want nil", err, got)
+		}
+	}
+}
+
+func TestFromDB(t *testing.T) {
+	business := New(ErrNotFound, "")
+	tests := []struct {
+		name     string
+		err      error
+		wantCode ErrorCode
+		wantNil  bool
+	}{
+		{name: "nil", err: nil, wantNil: true},
+		{name: "business error is returned as is", err: fmt.Errorf("wrapped: %w", business), wantCode: ErrNotFound},
+		{name: "mysql", err: &mysql.MySQLError{Number: 1062}, wantCode: ErrDuplicate},
+		{name: "unknown", err: stderrors.New("boom"), wantNil: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromDB(tt.err)
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("FromDB = %v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Code != tt.wantCode {
+				t.Fatalf("FromDB = %v, want code %d", got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package errors
+
+import (
+	stderrors "errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// PostgreSQL错误码(SQLSTATE)
+const (
+	pgUniqueViolation     = "23505" // 唯一键冲突
+	pgForeignKeyViolation = "23503" // 外键约束不满足
+	pgNotNullViolation    = "23502" // 非空列写入NULL
+	pgStringTooLong       = "22001" // 数据超出列长度
+	pgOutOfRange          = "22003" // 数值超出范围
+	pgInvalidText         = "22P02" // 数据格式不正确
+	pgDeadlockDetected    = "40P01" // 死锁
+	pgSerializationFailed = "40001" // 可串行化事务冲突
+	pgLockNotAvailable    = "55P03" // 锁等待超时
+)
+
+// FromPostgres 按SQLSTATE将PostgreSQL驱动错误转换为业务错误,无法识别时返回nil
+func FromPostgres(err error) *Error {
+	var pqErr *pq.Error
+	if !stderrors.As(err, &pqErr) {
+		return nil
+	}
+	detail := DBErrorDetail{SQLState: string(pqErr.Code), Column: pqErr.Column, Constraint: pqErr.Constraint}
+	var code ErrorCode
+	var message string
+	switch pqErr.Code {
+	case pgUniqueViolation:
+		code, message = ErrDuplicate, "记录已存在"
+	case pgForeignKeyViolation:
+		// 同一错误码既表示引用的记录不存在,也表示记录被其他数据引用,按详情区分
+		if strings.Contains(pqErr.Detail, "is still referenced") {
+			code, message = ErrConflict, "记录被其他数据引用,无法删除或修改"
+		} else {
+			code, message = ErrValidation, "引用的记录不存在"
+		}
+	case pgNotNullViolation:
+		code, message = ErrValidation, "缺少必填字段"
+	case pgStringTooLong:
+		code, message = ErrValidation, "数据超出长度限制"
+	case pgOutOfRange:
+		code, message = ErrValidation, "数值超出范围"
+	case pgInvalidText:
+		code, message = ErrValidation, "数据格式不正确"
+	case pgDeadlockDetected, pgSerializationFailed, pgLockNotAvailable:
+		code, message = ErrRetryable, "数据库繁忙,请稍后重试"
+		detail.Retryable = true
+	default:
+		return nil
+	}
+	return Wrap(code, message, err).WithDetails(detail)
+}
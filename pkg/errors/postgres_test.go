@@ -0,0 +1,113 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestFromPostgres(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   ErrorCode
+		wantDetail DBErrorDetail
+	}{
+		{
+			name:       "unique violation",
+			err:        &pq.Error{Code: "23505", Constraint: "authors_name_key"},
+			wantCode:   ErrDuplicate,
+			wantDetail: DBErrorDetail{SQLState: "23505", Constraint: "authors_name_key"},
+		},
+		{
+			name:       "referenced row is missing",
+			err:        &pq.Error{Code: "23503", Constraint: "books_author_id_fkey", Detail: `Key (author_id)=(9) is not present in table "authors".`},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{SQLState: "23503", Constraint: "books_author_id_fkey"},
+		},
+		{
+			name:       "row is still referenced",
+			err:        &pq.Error{Code: "23503", Constraint: "books_author_id_fkey", Detail: `Key (id)=(1) is still referenced from table "books".`},
+			wantCode:   ErrConflict,
+			wantDetail: DBErrorDetail{SQLState: "23503", Constraint: "books_author_id_fkey"},
+		},
+		{
+			name:       "not null violation",
+			err:        &pq.Error{Code: "23502", Column: "title"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{SQLState: "23502", Column: "title"},
+		},
+		{
+			name:       "string too long",
+			err:        &pq.Error{Code: "22001"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{SQLState: "22001"},
+		},
+		{
+			name:       "out of range",
+			err:        &pq.Error{Code: "22003"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{SQLState: "22003"},
+		},
+		{
+			name:       "invalid text representation",
+			err:        &pq.Error{Code: "22P02"},
+			wantCode:   ErrValidation,
+			wantDetail: DBErrorDetail{SQLState: "22P02"},
+		},
+		{
+			name:       "deadlock",
+			err:        &pq.Error{Code: "40P01"},
+			wantCode:   ErrRetryable,
+			wantDetail: DBErrorDetail{SQLState: "40P01", Retryable: true},
+		},
+		{
+			name:       "wrapped serialization failure",
+			err:        fmt.Errorf("failed to commit transaction: %w", &pq.Error{Code: "40001"}),
+			wantCode:   ErrRetryable,
+			wantDetail: DBErrorDetail{SQLState: "40001", Retryable: true},
+		},
+		{
+			name:       "lock not available",
+			err:        &pq.Error{Code: "55P03"},
+			wantCode:   ErrRetryable,
+			wantDetail: DBErrorDetail{SQLState: "55P03", Retryable: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromPostgres(tt.err)
+			if got == nil {
+				t.Fatal("FromPostgres returned nil")
+			}
+			if got.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", got.Code, tt.wantCode)
+			}
+			if !reflect.DeepEqual(got.Details, tt.wantDetail) {
+				t.Errorf("details = %+v, want %+v", got.Details, tt.wantDetail)
+			}
+			if got.Err != tt.err {
+				t.Errorf("the original error is not wrapped")
+			}
+			// FromDB同样识别PostgreSQL错误
+			if fromDB := FromDB(tt.err); fromDB == nil || fromDB.Code != tt.wantCode {
+				t.Errorf("FromDB = %v, want code %d", fromDB, tt.wantCode)
+			}
+		})
+	}
+
+	for _, err := range []error{
+		&pq.Error{Code: "42P01"},
+		&mysql.MySQLError{Number: 1062},
+		stderrors.New("connection refused"),
+		nil,
+	} {
+		if got := FromPostgres(err); got != nil {
+			t.Errorf("FromPostgres(%v) = %v, want nil", err, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package inflect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 不规则的单复数形式,单数 -> 复数
+var irregulars = map[string]string{
+	"person": "people",
+	"man":    "men",
+	"woman":  "women",
+	"child":  "children",
+	"tooth":  "teeth",
+	"foot":   "feet",
+	"mouse":  "mice",
+	"goose":  "geese",
+	"ox":     "oxen",
+	"leaf":   "leaves",
+	"knife":  "knives",
+	"life":   "lives",
+	"wife":   "wives",
+	"half":   "halves",
+	"shelf":  "shelves",
+	"wolf":   "wolves",
+	"thief":  "thieves",
+	"hero":   "heroes",
+	"potato": "potatoes",
+	"tomato": "tomatoes",
+	"echo":   "echoes",
+	"quiz":   "quizzes",
+	"index":  "indices",
+	"matrix": "matrices",
+	"vertex": "vertices",
+	"datum":  "data",
+	"medium": "media",
+	"crisis": "crises",
+	"axis":   "axes",
+	"status": "statuses",
+	"campus": "campuses",
+	"bus":    "buses",
+	// 以ie结尾的词,复数形式是规则的,但不能按 -ies -> -y 还原单数
+	"movie":    "movies",
+	"cookie":   "cookies",
+	"zombie":   "zombies",
+	"rookie":   "rookies",
+	"calorie":  "calories",
+	"selfie":   "selfies",
+	"smoothie": "smoothies",
+	"pie":      "pies",
+	"tie":      "ties",
+	"lie":      "lies",
+}
+
+// 复数 -> 单数,由irregulars反转得到
+var irregularSingulars = func() map[string]string {
+	result := make(map[string]string, len(irregulars))
+	for singular, plural := range irregulars {
+		result[plural] = singular
+	}
+	return result
+}()
+
+// 单复数同形的词
+var uncountables = map[string]struct{}{
+	"equipment": {}, "information": {}, "rice": {}, "money": {}, "species": {}, "series": {},
+	"fish": {}, "sheep": {}, "deer": {}, "news": {}, "metadata": {}, "feedback": {}, "software": {},
+}
+
+// Snake 将驼峰形式的名称转换为下划线分隔的小写形式,连续的大写字母视为一个单词
+// 例如 BookTag -> book_tag, HTTPRequest -> http_request, UserID -> user_id
+func Snake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Camel 将下划线分隔的名称转换为首字母大写的驼峰形式,例如 book_tag -> BookTag
+func Camel(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// Plural 返回下划线分隔的名称的复数形式,只变换最后一个单词,例如 book_category -> book_categories
+func Plural(name string) string {
+	prefix, word := splitLastWord(name)
+	return prefix + pluralWord(word)
+}
+
+// Singular 返回下划线分隔的名称的单数形式,只变换最后一个单词,例如 book_categories -> book_category
+func Singular(name string) string {
+	prefix, word := splitLastWord(name)
+	return prefix + singularWord(word)
+}
+
+// splitLastWord 拆分出最后一个单词,返回其之前的部分(包含下划线)和小写的最后一个单词
+func splitLastWord(name string) (string, string) {
+	idx := strings.LastIndex(name, "_")
+	return name[:idx+1], strings.ToLower(name[idx+1:])
+}
+
+func pluralWord(word string) string {
+	if _, ok := uncountables[word]; ok || word == "" {
+		return word
+	}
+	if plural, ok := irregulars[word]; ok {
+		return plural
+	}
+	if _, ok := irregularSingulars[word]; ok {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !isVowel(word[len(word)-2]):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "z"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	}
+	return word + "s"
+}
+
+func singularWord(word string) string {
+	if _, ok := uncountables[word]; ok || word == "" {
+		return word
+	}
+	if singular, ok := irregularSingulars[word]; ok {
+		return singular
+	}
+	if _, ok := irregulars[word]; ok {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "zes"),
+		strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
@@ -0,0 +1,80 @@
+package inflect
+
+import "testing"
+
+func TestSnake(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Book", "book"},
+		{"BookTag", "book_tag"},
+		{"HTTPRequest", "http_request"},
+		{"UserID", "user_id"},
+		{"OAuth2Token", "o_auth2_token"},
+		{"already_snake", "already_snake"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Snake(tt.in); got != tt.want {
+			t.Errorf("Snake(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCamel(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"book", "Book"},
+		{"book_tag", "BookTag"},
+		{"user__id_", "UserId"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Camel(tt.in); got != tt.want {
+			t.Errorf("Camel(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPluralAndSingular(t *testing.T) {
+	tests := []struct{ singular, plural string }{
+		{"book", "books"},
+		{"author", "authors"},
+		{"category", "categories"},
+		{"book_category", "book_categories"},
+		{"day", "days"},
+		{"key", "keys"},
+		{"box", "boxes"},
+		{"address", "addresses"},
+		{"match", "matches"},
+		{"dish", "dishes"},
+		{"quiz", "quizzes"},
+		{"movie", "movies"},
+		{"cookie", "cookies"},
+		{"pie", "pies"},
+		{"person", "people"},
+		{"user_person", "user_people"},
+		{"child", "children"},
+		{"leaf", "leaves"},
+		{"index", "indices"},
+		{"status", "statuses"},
+		{"bus", "buses"},
+		{"sheep", "sheep"},
+		{"news", "news"},
+		{"book_metadata", "book_metadata"},
+	}
+	for _, tt := range tests {
+		if got := Plural(tt.singular); got != tt.plural {
+			t.Errorf("Plural(%q) = %q, want %q", tt.singular, got, tt.plural)
+		}
+		if got := Singular(tt.plural); got != tt.singular {
+			t.Errorf("Singular(%q) = %q, want %q", tt.plural, got, tt.singular)
+		}
+	}
+}
+
+func TestSingularIsStable(t *testing.T) {
+	for _, word := range []string{"books", "people", "categories", "movies", "addresses", "statuses"} {
+		once := Singular(word)
+		if got := Singular(once); got != once {
+			t.Errorf("Singular(%q) = %q, Singular(%q) = %q", word, once, once, got)
+		}
+	}
+}
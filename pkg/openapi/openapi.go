@@ -0,0 +1,221 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version 生成的文档遵循的OpenAPI版本
+const Version = "3.0.3"
+
+// Document OpenAPI 3 文档,只包含本项目用到的部分
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info 文档基本信息
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Tag 接口分组
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem 单个路径下各HTTP方法的操作,键为小写的方法名
+type PathItem map[string]*Operation
+
+// Operation 单个接口
+type Operation struct {
+	Tags        []string             `json:"tags,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	OperationID string               `json:"operationId,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter 路径、查询或请求头参数,Ref不为空时引用components中的参数
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Style       string  `json:"style,omitempty"`   // 数组参数的序列化方式,例如form
+	Explode     *bool   `json:"explode,omitempty"` // 为false时数组以逗号分隔的单个参数传递
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// Response 响应,Ref不为空时引用components中的响应
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header 响应头
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// MediaType 请求体或响应的内容
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components 可复用的结构、参数和响应
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas,omitempty"`
+	Parameters map[string]*Parameter `json:"parameters,omitempty"`
+	Responses  map[string]*Response  `json:"responses,omitempty"`
+}
+
+// Schema 数据结构,Ref不为空时引用components中的结构
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	OneOf       []*Schema          `json:"oneOf,omitempty"`
+	AllOf       []*Schema          `json:"allOf,omitempty"`
+}
+
+// New 创建空文档
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:    make(map[string]*Schema),
+			Parameters: make(map[string]*Parameter),
+			Responses:  make(map[string]*Response),
+		},
+	}
+}
+
+// AddOperation 添加接口,method不区分大小写
+func (d *Document) AddOperation(method, path string, op *Operation) {
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// SchemaRef 引用components中名为name的结构
+func SchemaRef(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// ParameterRef 引用components中名为name的参数
+func ParameterRef(name string) *Parameter {
+	return &Parameter{Ref: "#/components/parameters/" + name}
+}
+
+// ResponseRef 引用components中名为name的响应
+func ResponseRef(name string) *Response {
+	return &Response{Ref: "#/components/responses/" + name}
+}
+
+// JSON 以application/json为内容类型的请求体或响应内容
+func JSON(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// ArrayOf 元素为items的数组
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf 根据Go类型推导结构,结构体按json标签生成属性,指针类型可为null
+func SchemaOf(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		schema := SchemaOf(t.Elem())
+		schema.Nullable = true
+		return schema
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return ArrayOf(SchemaOf(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, ok := JSONName(field)
+			if !ok {
+				continue
+			}
+			schema.Properties[name] = SchemaOf(field.Type)
+		}
+		return schema
+	}
+	return &Schema{}
+}
+
+// JSONName 返回字段序列化后的名称,未导出或json标签为"-"的字段返回false
+func JSONName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
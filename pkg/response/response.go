@@ -10,12 +10,13 @@ import (
 
 // Response 统一响应结构
 type Response struct {
-	Code      int         `json:"code"`            // 业务错误码
-	CodeDesc  string      `json:"code_desc"`       // 错误码说明
-	Message   string      `json:"message"`         // 响应消息
-	Data      interface{} `json:"data,omitempty"`  // 响应数据
-	Timestamp int64       `json:"timestamp"`       // 时间戳
-	Error     string      `json:"error,omitempty"` // 错误信息
+	Code      int         `json:"code"`              // 业务错误码
+	CodeDesc  string      `json:"code_desc"`         // 错误码说明
+	Message   string      `json:"message"`           // 响应消息
+	Data      interface{} `json:"data,omitempty"`    // 响应数据
+	Timestamp int64       `json:"timestamp"`         // 时间戳
+	Error     string      `json:"error,omitempty"`   // 错误信息
+	Details   interface{} `json:"details,omitempty"` // 错误详情
 }
 
 // Success 成功响应
@@ -38,7 +39,11 @@ func SystemError(err error) *errors.Error {
 	return Error(errors.ErrSystem, err, "")
 }
 
+// DatabaseError 数据库错误,唯一键冲突、外键约束、死锁等可识别的错误转换为对应的业务错误
 func DatabaseError(err error) *errors.Error {
+	if e := errors.FromDB(err); e != nil {
+		return e
+	}
 	return Error(errors.ErrDatabase, err, "")
 }
 